@@ -3,6 +3,7 @@ package backend
 import (
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -22,9 +23,18 @@ func (s *Server) getMachineProtocol(c *gin.Context) {
 	c.JSON(200, rsp)
 }
 
+type MachinePartition struct {
+	Mountpoint string `json:"mountpoint"`
+	Device     string `json:"device"`
+	Fstype     string `json:"fstype"`
+}
+
+// getMachineDiskPartition lists the mount points, and the partitions with their fstype.
+// The virtual file systems like tmpfs are listed with ?all=true.
 func (s *Server) getMachineDiskPartition(c *gin.Context) {
 	rsp := &Response{}
-	stat, err := disk.Partitions(false)
+	all, _ := strconv.ParseBool(c.Query("all"))
+	stat, err := disk.Partitions(all)
 	if err != nil {
 		rsp.Reason = err.Error()
 		c.JSON(500, rsp)
@@ -32,16 +42,22 @@ func (s *Server) getMachineDiskPartition(c *gin.Context) {
 	}
 	rsp.Success, rsp.Reason = true, "success"
 	dev := []string{}
+	partitions := []MachinePartition{}
 	for _, v := range stat {
 		if runtime.GOOS == "darwin" {
 			if strings.HasPrefix(v.Mountpoint, "/System/Volumes") {
 				continue
 			}
 		}
+		if slices.Contains(dev, v.Mountpoint) {
+			continue
+		}
 		dev = append(dev, v.Mountpoint)
+		partitions = append(partitions, MachinePartition{Mountpoint: v.Mountpoint, Device: v.Device, Fstype: v.Fstype})
 	}
 	slices.Sort(dev)
-	rsp.Data = gin.H{"partition": dev}
+	slices.SortFunc(partitions, func(a, b MachinePartition) int { return strings.Compare(a.Mountpoint, b.Mountpoint) })
+	rsp.Data = gin.H{"partition": dev, "partitions": partitions}
 	c.JSON(200, rsp)
 }
 
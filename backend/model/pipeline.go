@@ -0,0 +1,46 @@
+package model
+
+import "fmt"
+
+// Pipeline is a named pipeline that has its own configs,
+// the keys are the same as the global configs, e.g. "interval", "in_disk".
+//
+// The plugins of the pipeline are listed in Inputs, Processors and Outputs.
+// If none of them is listed, the plugins are derived from the configs
+// as the global configs do, e.g. "in_disk", "proc_rate" and "table_name".
+type Pipeline struct {
+	Name       string            `json:"name"`
+	Configs    map[string]string `json:"configs,omitempty"`
+	Inputs     []PluginInstance  `json:"inputs,omitempty"`
+	Processors []PluginInstance  `json:"processors,omitempty"`
+	Outputs    []PluginInstance  `json:"outputs,omitempty"`
+}
+
+func (p *Pipeline) GetConfig(key string) (string, error) {
+	if val, ok := p.Configs[key]; ok {
+		return val, nil
+	}
+	return "", fmt.Errorf("config %q not found", key)
+}
+
+// Declarative returns true if the pipeline lists its plugins.
+func (p *Pipeline) Declarative() bool {
+	return len(p.Inputs) > 0 || len(p.Processors) > 0 || len(p.Outputs) > 0
+}
+
+// PluginInstance is an instance of a registered plugin, e.g. in-disk, proc-rate, out-mqtt.
+// A plugin can have several instances with different args,
+// they are identified by the Name that is the Plugin if not set.
+type PluginInstance struct {
+	Name    string            `json:"name,omitempty"`
+	Plugin  string            `json:"plugin"`
+	Args    []string          `json:"args,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+func (pi *PluginInstance) InstanceName() string {
+	if pi.Name != "" {
+		return pi.Name
+	}
+	return pi.Plugin
+}
@@ -1,10 +1,20 @@
 package backend
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"neo-cat/backend/model"
 	"neo-cat/backend/pstag"
 	"neo-cat/backend/pstag/plugin"
+	"neo-cat/backend/pstag/report"
+	"neo-cat/backend/store"
+	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -20,74 +30,801 @@ const (
 	CONF_IN_HOST               = "in_host"
 	CONF_IN_PROTO              = "in_proto"
 	CONF_IN_DISK               = "in_disk"
+	CONF_IN_DISK_EXCLUDE       = "in_disk_exclude"
+	CONF_IN_DISK_FSTYPES       = "in_disk_fstypes"
+	CONF_IN_DISK_EXCL_FSTYPES  = "in_disk_exclude_fstypes"
 	CONF_IN_DISKIO             = "in_diskio"
+	CONF_IN_DISKIO_EXCLUDE     = "in_diskio_exclude"
 	CONF_IN_NET                = "in_net"
+	CONF_IN_NEO_STATZ          = "in_neo_statz"
+	CONF_IN_PROCSTAT           = "in_procstat"
+	CONF_IN_TOP                = "in_top"
+	CONF_IN_CGROUP             = "in_cgroup"
+	CONF_IN_PRESSURE           = "in_pressure"
+	CONF_IN_KERNEL             = "in_kernel"
+	CONF_TAGS                  = "tags"
+	CONF_NAME_TEMPLATE         = "name_template"
+	CONF_TAGS_COLUMN           = "tags_column"
+	CONF_PROC_INCLUDE          = "proc_include"
+	CONF_PROC_EXCLUDE          = "proc_exclude"
+	CONF_PROC_RENAME           = "proc_rename"
+	CONF_PROC_SCALE            = "proc_scale"
+	CONF_PROC_RATE             = "proc_rate"
+	CONF_PROC_RATE_MODE        = "proc_rate_mode"
+	CONF_PROC_DROP_NAN         = "proc_drop_nan"
+	CONF_PROC_AGGREGATE        = "proc_aggregate"
+	CONF_SPOOL_DIR             = "spool_dir"
+	CONF_SPOOL_MAX_SIZE        = "spool_max_size"
+	CONF_SPOOL_MAX_AGE         = "spool_max_age"
+	CONF_SELF_STATS            = "self_stats"
+	CONF_OUT_QUEUE_SIZE        = "out_queue_size"
+	CONF_OUT_OVERFLOW          = "out_overflow"
+	CONF_ALIGN                 = "align"
+	CONF_SPLAY                 = "splay"
+
+	// CONF_INTERVAL_SUFFIX is appended to an inlet key to configure
+	// the interval of the inlet, e.g. "in_table_rows_counter_interval".
+	CONF_INTERVAL_SUFFIX = "_interval"
+	// CONF_TIMEOUT_SUFFIX is appended to an inlet key to configure
+	// the deadline of a collection, the interval of the inlet by default.
+	CONF_TIMEOUT_SUFFIX = "_timeout"
 )
 
-func (s *Server) StartProcess() error {
-	var interval time.Duration
-	if val, err := s.data.GetConfig(CONF_INTERVAL); err != nil {
-		return fmt.Errorf("interval not configured")
-	} else {
-		interval, err = time.ParseDuration(val)
-		if err != nil {
-			return fmt.Errorf("interval %q is wrong value", val)
+// DefaultPipeline is the pipeline that is configured by the global configs.
+// Its plugin instances can be defined declaratively as the other pipelines,
+// they are stored in the pipelines table without the configs.
+const DefaultPipeline = "default"
+
+// processSpec is the configuration of the process that is read from the store.
+// The spec of the running process is kept to apply only the changes of the configuration.
+type processSpec struct {
+	interval   time.Duration
+	tagPrefix  string
+	tags       []report.Tag
+	selfStats  bool
+	inputs     []inputSpec
+	processors []processorSpec
+	outputs    []outputSpec
+}
+
+type inputSpec struct {
+	name     string
+	inlet    string
+	args     []string
+	interval time.Duration
+	timeout  time.Duration
+	align    bool
+	splay    time.Duration
+}
+
+type processorSpec struct {
+	processor string
+	args      []string
+}
+
+type outputSpec struct {
+	name      string
+	outlet    string
+	args      []string
+	spool     spoolSpec
+	queueSize int
+	overflow  pstag.OverflowPolicy
+}
+
+type spoolSpec struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+}
+
+// processConf reads the configs of a pipeline,
+// the default pipeline reads the global configs and the others read their own.
+type processConf struct {
+	pipeline string
+	configs  interface {
+		GetConfig(key string) (string, error)
+	}
+}
+
+func (conf *processConf) GetConfig(key string) (string, error) {
+	return conf.configs.GetConfig(key)
+}
+
+// enabled returns true unless the config of the key is "false",
+// it is for the inlets that are enabled by default in the default pipeline.
+// The other pipelines start with no inlets, they are enabled by "true" or the args.
+func (conf *processConf) enabled(key string) bool {
+	if val, err := conf.GetConfig(key); err == nil && strings.TrimSpace(val) != "" {
+		if ok, err := strconv.ParseBool(strings.TrimSpace(val)); err == nil {
+			return ok
 		}
+		return true
 	}
-	tableName, _ := s.data.GetConfig(CONF_TABLE_NAME)
-	tagPrefix, _ := s.data.GetConfig(CONF_TAG_PREFIX)
-	neoCounters := []string{}
-	if tables, _ := s.data.GetConfig(CONF_IN_TABLE_ROWS_COUNTER); tables != "" {
-		neoCounters = strings.Split(tables, ",")
+	return conf.pipeline == DefaultPipeline
+}
+
+// optionalArgs returns the args of the inlet that is disabled by default,
+// the config is "true" to enable it or the space separated args.
+func (conf *processConf) optionalArgs(key string) ([]string, bool) {
+	val, err := conf.GetConfig(key)
+	if err != nil || strings.TrimSpace(val) == "" {
+		return nil, false
 	}
+	if ok, err := strconv.ParseBool(strings.TrimSpace(val)); err == nil {
+		return nil, ok
+	}
+	return strings.Fields(val), true
+}
 
-	if s.process != nil && s.process.Running() {
-		return fmt.Errorf("process is already running")
+// loadPipeline reads the pipeline, the default pipeline is read from the global configs
+// and its plugin instances if they have been defined.
+func (s *Server) loadPipeline(name string) (*model.Pipeline, error) {
+	if name != DefaultPipeline {
+		return s.data.GetPipeline(name)
+	}
+	configs, err := s.data.GetConfigs()
+	if err != nil {
+		return nil, err
 	}
+	ret := &model.Pipeline{Name: name, Configs: configs}
+	def, err := s.data.GetPipeline(name)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	} else if err == nil {
+		ret.Inputs, ret.Processors, ret.Outputs = def.Inputs, def.Processors, def.Outputs
+	}
+	return ret, nil
+}
 
-	s.process = pstag.New(
-		pstag.WithInterval(interval),
-		pstag.WithTagPrefix(tagPrefix),
-	)
-	s.process.AddInput(plugin.NewInlet("in-load"))
-	s.process.AddInput(plugin.NewInlet("in-cpu"))
-	s.process.AddInput(plugin.NewInlet("in-mem"))
-	s.process.AddInput(plugin.NewInlet("in-host"))
-	if val, err := s.data.GetConfig(CONF_IN_PROTO); err == nil && strings.TrimSpace(val) != "" {
+func (s *Server) loadProcessSpec(name string) (*processSpec, error) {
+	p, err := s.loadPipeline(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildProcessSpec(p)
+}
+
+// configPlugins lists the plugin instances from the configs of the pipeline,
+// that are the settings of the UI, e.g. "in_disk", "proc_rate" and "table_name".
+func (s *Server) configPlugins(p *model.Pipeline) *model.Pipeline {
+	conf := &processConf{pipeline: p.Name, configs: p}
+	ret := *p
+
+	inputs := []model.PluginInstance{}
+	for _, in := range []model.PluginInstance{
+		{Name: CONF_IN_LOAD, Plugin: "in-load"},
+		{Name: CONF_IN_CPU, Plugin: "in-cpu"},
+		{Name: CONF_IN_MEM, Plugin: "in-mem"},
+		{Name: CONF_IN_HOST, Plugin: "in-host"},
+	} {
+		if !conf.enabled(in.Name) {
+			continue
+		}
+		// the value that is not a bool is the args, e.g. in_cpu "total,times"
+		if val, err := conf.GetConfig(in.Name); err == nil && len(plugin.GetInletRegistry(in.Plugin).Args) > 0 {
+			if _, err := strconv.ParseBool(strings.TrimSpace(val)); err != nil && strings.TrimSpace(val) != "" {
+				in.Args = []string{strings.TrimSpace(val)}
+			}
+		}
+		inputs = append(inputs, in)
+	}
+	if val, err := conf.GetConfig(CONF_IN_PROTO); err == nil && strings.TrimSpace(val) != "" {
 		if runtime.GOOS != "darwin" {
-			s.process.AddInput(plugin.NewInlet("in-proto", val))
+			inputs = append(inputs, model.PluginInstance{Name: CONF_IN_PROTO, Plugin: "in-proto", Args: []string{val}})
 		}
 	}
-	if val, err := s.data.GetConfig(CONF_IN_DISK); err == nil && strings.TrimSpace(val) != "" {
-		s.process.AddInput(plugin.NewInlet("in-disk", val))
+	if val, err := conf.GetConfig(CONF_IN_DISK); err == nil && strings.TrimSpace(val) != "" {
+		args := []string{val}
+		for _, opt := range [][2]string{
+			{CONF_IN_DISK_EXCLUDE, "exclude"},
+			{CONF_IN_DISK_FSTYPES, "fstypes"},
+			{CONF_IN_DISK_EXCL_FSTYPES, "exclude_fstypes"},
+		} {
+			if v, err := conf.GetConfig(opt[0]); err == nil && strings.TrimSpace(v) != "" {
+				args = append(args, opt[1]+"="+strings.TrimSpace(v))
+			}
+		}
+		inputs = append(inputs, model.PluginInstance{Name: CONF_IN_DISK, Plugin: "in-disk", Args: args})
+	}
+	if val, err := conf.GetConfig(CONF_IN_DISKIO); err == nil && strings.TrimSpace(val) != "" {
+		args := []string{val}
+		if exclude, err := conf.GetConfig(CONF_IN_DISKIO_EXCLUDE); err == nil && strings.TrimSpace(exclude) != "" {
+			args = append(args, "exclude="+strings.TrimSpace(exclude))
+		}
+		inputs = append(inputs, model.PluginInstance{Name: CONF_IN_DISKIO, Plugin: "in-diskio", Args: args})
+	}
+	if val, err := conf.GetConfig(CONF_IN_NET); err == nil && strings.TrimSpace(val) != "" {
+		inputs = append(inputs, model.PluginInstance{Name: CONF_IN_NET, Plugin: "in-net", Args: []string{val}})
+	}
+	if val, err := conf.GetConfig(CONF_IN_CGROUP); err == nil && strings.TrimSpace(val) != "" {
+		inputs = append(inputs, model.PluginInstance{Name: CONF_IN_CGROUP, Plugin: "in-cgroup", Args: []string{val}})
+	}
+	// the selectors of the processes, space separated, e.g. "name=machbase-neo"
+	if val, err := conf.GetConfig(CONF_IN_PROCSTAT); err == nil && strings.TrimSpace(val) != "" {
+		inputs = append(inputs, model.PluginInstance{Name: CONF_IN_PROCSTAT, Plugin: "in-procstat", Args: strings.Fields(val)})
 	}
-	if val, err := s.data.GetConfig(CONF_IN_DISKIO); err == nil && strings.TrimSpace(val) != "" {
-		s.process.AddInput(plugin.NewInlet("in-diskio", val))
+	for _, in := range []model.PluginInstance{
+		{Name: CONF_IN_PRESSURE, Plugin: "in-pressure"}, // e.g. "true", "cpu,io"
+		{Name: CONF_IN_TOP, Plugin: "in-top"},           // e.g. "true", "5 50"
+		{Name: CONF_IN_KERNEL, Plugin: "in-kernel"},     // e.g. "true"
+	} {
+		if args, ok := conf.optionalArgs(in.Name); ok {
+			in.Args = args
+			inputs = append(inputs, in)
+		}
 	}
-	if val, err := s.data.GetConfig(CONF_IN_NET); err == nil && strings.TrimSpace(val) != "" {
-		s.process.AddInput(plugin.NewInlet("in-net", val))
+	if conf.enabled(CONF_IN_NEO_STATZ) {
+		inputs = append(inputs, model.PluginInstance{Name: CONF_IN_NEO_STATZ, Plugin: "in-neo-statz", Args: []string{s.neoHttpAddr}})
 	}
-	s.process.AddInput(plugin.NewInlet("in-neo-statz", s.neoHttpAddr))
-	if len(neoCounters) > 0 {
-		s.process.AddInput(plugin.NewInlet("in-neo-table-rows-counter", append([]string{s.neoHttpAddr}, neoCounters...)...))
+	if tables, _ := conf.GetConfig(CONF_IN_TABLE_ROWS_COUNTER); tables != "" {
+		inputs = append(inputs, model.PluginInstance{Name: CONF_IN_TABLE_ROWS_COUNTER, Plugin: "in-neo-table-rows-counter",
+			Args: append([]string{s.neoHttpAddr}, strings.Split(tables, ",")...)})
 	}
-	if tableName != "" {
-		s.process.AddOutput(plugin.NewOutlet("out-mqtt", fmt.Sprintf("tcp://127.0.0.1:5653/db/append/%s:csv", tableName)))
+	for i, in := range inputs {
+		options := map[string]string{}
+		if val, err := conf.GetConfig(in.Name + CONF_INTERVAL_SUFFIX); err == nil && strings.TrimSpace(val) != "" {
+			options["interval"] = val
+		}
+		if val, err := conf.GetConfig(in.Name + CONF_TIMEOUT_SUFFIX); err == nil && strings.TrimSpace(val) != "" {
+			options["timeout"] = val
+		}
+		if len(options) > 0 {
+			inputs[i].Options = options
+		}
+	}
+	ret.Inputs = inputs
+
+	ret.Processors = conf.processorPlugins()
+
+	ret.Outputs = []model.PluginInstance{}
+	if tableName, _ := conf.GetConfig(CONF_TABLE_NAME); tableName != "" {
+		ret.Outputs = append(ret.Outputs, model.PluginInstance{
+			Plugin: "out-mqtt",
+			Args:   append([]string{fmt.Sprintf("tcp://127.0.0.1:5653/db/append/%s:csv", tableName)}, conf.outletFormatArgs()...),
+		})
 	}
 	if s.debugMode {
-		s.process.AddOutput(plugin.NewOutlet("out-file", "-"))
+		ret.Outputs = append(ret.Outputs, model.PluginInstance{
+			Plugin: "out-file",
+			Args:   append([]string{"-"}, conf.outletFormatArgs()...),
+		})
+	}
+	return &ret
+}
+
+var (
+	inputOptionKeys  = []string{"interval", "timeout", "align", "splay"}
+	outputOptionKeys = []string{"queue_size", "overflow", CONF_SPOOL_DIR, CONF_SPOOL_MAX_SIZE, CONF_SPOOL_MAX_AGE}
+)
+
+// buildProcessSpec builds the spec from the plugin instances of the pipeline,
+// the configs of the pipeline are the defaults of the options of the instances.
+func (s *Server) buildProcessSpec(p *model.Pipeline) (*processSpec, error) {
+	if !p.Declarative() {
+		p = s.configPlugins(p)
+	}
+	conf := &processConf{pipeline: p.Name, configs: p}
+	ret := &processSpec{}
+	if val, err := conf.GetConfig(CONF_INTERVAL); err != nil {
+		return nil, fmt.Errorf("interval not configured")
+	} else {
+		ret.interval, err = time.ParseDuration(val)
+		if err != nil {
+			return nil, fmt.Errorf("interval %q is wrong value", val)
+		}
+	}
+	ret.tagPrefix, _ = conf.GetConfig(CONF_TAG_PREFIX)
+	if val, err := conf.GetConfig(CONF_TAGS); err == nil {
+		ret.tags = parseTags(val)
+	}
+	if val, err := conf.GetConfig(CONF_SELF_STATS); err == nil {
+		ret.selfStats, _ = strconv.ParseBool(strings.TrimSpace(val))
+	}
+
+	align, splay := false, time.Duration(0)
+	if val, err := conf.GetConfig(CONF_ALIGN); err == nil && strings.TrimSpace(val) != "" {
+		if align, err = strconv.ParseBool(strings.TrimSpace(val)); err != nil {
+			return nil, fmt.Errorf("%s %q is wrong value", CONF_ALIGN, val)
+		}
+	}
+	if val, err := conf.GetConfig(CONF_SPLAY); err == nil && strings.TrimSpace(val) != "" {
+		if splay, err = time.ParseDuration(strings.TrimSpace(val)); err != nil {
+			return nil, fmt.Errorf("%s %q is wrong value", CONF_SPLAY, val)
+		}
+	}
+	for _, inst := range p.Inputs {
+		if reg := plugin.GetInletRegistry(inst.Plugin); reg == nil {
+			return nil, fmt.Errorf("unknown inlet %q", inst.Plugin)
+		} else if err := reg.Args.Validate(inst.Args); err != nil {
+			return nil, fmt.Errorf("inlet %s, %s", inst.InstanceName(), err)
+		}
+		if err := checkOptions(inst, inputOptionKeys); err != nil {
+			return nil, err
+		}
+		in := inputSpec{name: inst.InstanceName(), inlet: inst.Plugin, args: inst.Args}
+		if slices.ContainsFunc(ret.inputs, func(o inputSpec) bool { return o.name == in.name }) {
+			return nil, fmt.Errorf("input %q is duplicated, give it a name", in.name)
+		}
+		var err error
+		if in.interval, err = optionDuration(inst, "interval", ret.interval); err != nil {
+			return nil, err
+		}
+		if in.timeout, err = optionDuration(inst, "timeout", 0); err != nil {
+			return nil, err
+		}
+		if in.align, err = optionBool(inst, "align", align); err != nil {
+			return nil, err
+		}
+		if in.splay, err = optionDuration(inst, "splay", splay); err != nil {
+			return nil, err
+		}
+		ret.inputs = append(ret.inputs, in)
+	}
+
+	for _, inst := range p.Processors {
+		if reg := plugin.GetProcessorRegistry(inst.Plugin); reg == nil {
+			return nil, fmt.Errorf("unknown processor %q", inst.Plugin)
+		} else if err := reg.Args.Validate(inst.Args); err != nil {
+			return nil, fmt.Errorf("processor %s, %s", inst.InstanceName(), err)
+		}
+		// the processors have no options
+		if err := checkOptions(inst, nil); err != nil {
+			return nil, err
+		}
+		ret.processors = append(ret.processors, processorSpec{processor: inst.Plugin, args: inst.Args})
+	}
+
+	queueSize, overflow := pstag.DefaultQueueSize, pstag.OverflowDropOldest
+	if val, err := conf.GetConfig(CONF_OUT_QUEUE_SIZE); err == nil && strings.TrimSpace(val) != "" {
+		if queueSize, err = strconv.Atoi(strings.TrimSpace(val)); err != nil || queueSize < 1 {
+			return nil, fmt.Errorf("%s %q is wrong value", CONF_OUT_QUEUE_SIZE, val)
+		}
+	}
+	if val, err := conf.GetConfig(CONF_OUT_OVERFLOW); err == nil && strings.TrimSpace(val) != "" {
+		if overflow, err = pstag.ParseOverflowPolicy(val); err != nil {
+			return nil, err
+		}
+	}
+	for _, inst := range p.Outputs {
+		if reg := plugin.GetOutletRegistry(inst.Plugin); reg == nil {
+			return nil, fmt.Errorf("unknown outlet %q", inst.Plugin)
+		} else if err := reg.Args.Validate(inst.Args); err != nil {
+			return nil, fmt.Errorf("outlet %s, %s", inst.InstanceName(), err)
+		}
+		if err := checkOptions(inst, outputOptionKeys); err != nil {
+			return nil, err
+		}
+		out := outputSpec{name: inst.InstanceName(), outlet: inst.Plugin, args: inst.Args, queueSize: queueSize, overflow: overflow}
+		if slices.ContainsFunc(ret.outputs, func(o outputSpec) bool { return o.name == out.name }) {
+			return nil, fmt.Errorf("output %q is duplicated, give it a name", out.name)
+		}
+		if val, ok := inst.Options["queue_size"]; ok {
+			var err error
+			if out.queueSize, err = strconv.Atoi(strings.TrimSpace(val)); err != nil || out.queueSize < 1 {
+				return nil, fmt.Errorf("%s queue_size %q is wrong value", out.name, val)
+			}
+		}
+		if val, ok := inst.Options["overflow"]; ok {
+			var err error
+			if out.overflow, err = pstag.ParseOverflowPolicy(val); err != nil {
+				return nil, err
+			}
+		}
+		spool, err := conf.spoolSpec(out.name, inst.Options)
+		if err != nil {
+			return nil, err
+		}
+		out.spool = spool
+		ret.outputs = append(ret.outputs, out)
+	}
+	return ret, nil
+}
+
+func checkOptions(inst model.PluginInstance, keys []string) error {
+	for k := range inst.Options {
+		if !slices.Contains(keys, k) {
+			return fmt.Errorf("%s unknown option %q, available: %s", inst.InstanceName(), k, strings.Join(keys, ","))
+		}
 	}
-	s.process.Run()
 	return nil
 }
 
-func (s *Server) StopProcess() {
-	if s.process != nil && s.process.Running() {
-		s.process.Stop()
+func optionDuration(inst model.PluginInstance, key string, def time.Duration) (time.Duration, error) {
+	val, ok := inst.Options[key]
+	if !ok || strings.TrimSpace(val) == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(val))
+	if err != nil {
+		return 0, fmt.Errorf("%s %s %q is wrong value", inst.InstanceName(), key, val)
+	}
+	return d, nil
+}
+
+func optionBool(inst model.PluginInstance, key string, def bool) (bool, error) {
+	val, ok := inst.Options[key]
+	if !ok || strings.TrimSpace(val) == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(val))
+	if err != nil {
+		return false, fmt.Errorf("%s %s %q is wrong value", inst.InstanceName(), key, val)
+	}
+	return b, nil
+}
+
+func (spec inputSpec) options() []pstag.InputOption {
+	return []pstag.InputOption{
+		pstag.WithInputName(spec.name),
+		pstag.WithInputInterval(spec.interval),
+		pstag.WithInputTimeout(spec.timeout),
+		pstag.WithInputAlign(spec.align),
+		pstag.WithInputSplay(spec.splay),
 	}
 }
 
-func (s *Server) RestartProcess() {
-	s.StopProcess()
-	s.StartProcess()
+func (spec outputSpec) options() []pstag.OutputOption {
+	ret := []pstag.OutputOption{
+		pstag.WithOutputName(spec.name),
+		pstag.WithQueue(spec.queueSize, spec.overflow),
+	}
+	if spec.spool.dir != "" {
+		ret = append(ret, pstag.WithSpool(spec.spool.dir, spec.spool.maxSize, spec.spool.maxAge))
+	}
+	return ret
+}
+
+func newProcessors(specs []processorSpec) []report.Processor {
+	ret := make([]report.Processor, 0, len(specs))
+	for _, spec := range specs {
+		ret = append(ret, plugin.NewProcessor(spec.processor, spec.args...))
+	}
+	return ret
+}
+
+// reuseProcessors returns the processors of the specs, that keeps the instances
+// of the old specs that have not been changed, so that the states of them,
+// e.g. the previous samples of proc-rate and the windows of proc-aggregate, are kept.
+func reuseProcessors(oldSpecs []processorSpec, oldProcs []report.Processor, specs []processorSpec) []report.Processor {
+	used := make([]bool, len(oldSpecs))
+	ret := make([]report.Processor, 0, len(specs))
+	for _, spec := range specs {
+		var proc report.Processor
+		for i, o := range oldSpecs {
+			if !used[i] && i < len(oldProcs) && reflect.DeepEqual(o, spec) {
+				used[i], proc = true, oldProcs[i]
+				break
+			}
+		}
+		if proc == nil {
+			proc = plugin.NewProcessor(spec.processor, spec.args...)
+		}
+		ret = append(ret, proc)
+	}
+	return ret
+}
+
+// pipeline is a process built from the configs of a name.
+type pipeline struct {
+	process *pstag.PsTag
+	spec    *processSpec
+	// processors are the instances of the spec.processors in the process
+	processors []report.Processor
+}
+
+// runningProcess returns the process of the pipeline, or nil if it is not running.
+func (s *Server) runningProcess(name string) *pstag.PsTag {
+	s.processLock.Lock()
+	defer s.processLock.Unlock()
+	if p, ok := s.pipelines[name]; ok && p.process.Running() {
+		return p.process
+	}
+	return nil
+}
+
+// StartPipelines starts the default pipeline and the stored pipelines,
+// the errors are logged as they may be not configured yet.
+func (s *Server) StartPipelines() {
+	names := []string{DefaultPipeline}
+	if list, err := s.data.GetPipelines(); err == nil {
+		for _, p := range list {
+			if p.Name != DefaultPipeline {
+				names = append(names, p.Name)
+			}
+		}
+	}
+	for _, name := range names {
+		if err := s.StartProcess(name); err != nil {
+			slog.Warn("failed to start pipeline", "pipeline", name, "error", err.Error())
+		}
+	}
+}
+
+func (s *Server) StopPipelines() {
+	s.processLock.Lock()
+	defer s.processLock.Unlock()
+	for name := range s.pipelines {
+		s.stopProcess(name)
+	}
+}
+
+func (s *Server) StartProcess(name string) error {
+	s.processLock.Lock()
+	defer s.processLock.Unlock()
+	return s.startProcess(name)
+}
+
+func (s *Server) startProcess(name string) error {
+	spec, err := s.loadProcessSpec(name)
+	if err != nil {
+		return err
+	}
+
+	if p, ok := s.pipelines[name]; ok && p.process.Running() {
+		return fmt.Errorf("pipeline %q is already running", name)
+	}
+
+	process := pstag.New(
+		pstag.WithInterval(spec.interval),
+		pstag.WithTagPrefix(spec.tagPrefix),
+		pstag.WithTags(spec.tags),
+		pstag.WithSelfStats(spec.selfStats),
+	)
+	errs := []error{}
+	for _, in := range spec.inputs {
+		errs = append(errs, process.AddInput(plugin.NewInlet(in.inlet, in.args...), in.options()...))
+	}
+	processors := newProcessors(spec.processors)
+	for _, proc := range processors {
+		errs = append(errs, process.AddProcessor(proc))
+	}
+	for _, out := range spec.outputs {
+		errs = append(errs, process.AddOutput(plugin.NewOutlet(out.outlet, out.args...), out.options()...))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	if s.pipelines == nil {
+		s.pipelines = map[string]*pipeline{}
+	}
+	s.pipelines[name] = &pipeline{process: process, spec: spec, processors: processors}
+	return process.Run()
+}
+
+// ReloadProcess applies the changes of the configuration to the running process,
+// only the inputs, processors and outputs that have been changed are replaced.
+func (s *Server) ReloadProcess(name string) error {
+	s.processLock.Lock()
+	defer s.processLock.Unlock()
+	p, ok := s.pipelines[name]
+	if !ok || !p.process.Running() {
+		return nil
+	}
+	spec, err := s.loadProcessSpec(name)
+	if err != nil {
+		return err
+	}
+	old, process := p.spec, p.process
+	if old.interval != spec.interval {
+		// the outputs flush by the interval of the process
+		s.stopProcess(name)
+		return s.startProcess(name)
+	}
+	errs := []error{}
+
+	process.SetTagPrefix(spec.tagPrefix)
+	process.SetTags(spec.tags)
+	errs = append(errs, process.SetSelfStats(spec.selfStats))
+
+	for _, in := range old.inputs {
+		if !slices.ContainsFunc(spec.inputs, func(n inputSpec) bool { return n.name == in.name }) {
+			errs = append(errs, process.RemoveInput(in.name))
+		}
+	}
+	for _, in := range spec.inputs {
+		idx := slices.IndexFunc(old.inputs, func(o inputSpec) bool { return o.name == in.name })
+		if idx < 0 {
+			errs = append(errs, process.AddInput(plugin.NewInlet(in.inlet, in.args...), in.options()...))
+		} else if !reflect.DeepEqual(old.inputs[idx], in) {
+			errs = append(errs, process.ReplaceInput(in.name, plugin.NewInlet(in.inlet, in.args...), in.options()...))
+		}
+	}
+
+	if !reflect.DeepEqual(old.processors, spec.processors) {
+		processors := reuseProcessors(old.processors, p.processors, spec.processors)
+		if err := process.SetProcessors(processors); err != nil {
+			errs = append(errs, err)
+		} else {
+			p.processors = processors
+		}
+	}
+
+	for _, out := range old.outputs {
+		if !slices.ContainsFunc(spec.outputs, func(n outputSpec) bool { return n.name == out.name }) {
+			errs = append(errs, process.RemoveOutput(out.name))
+		}
+	}
+	for _, out := range spec.outputs {
+		idx := slices.IndexFunc(old.outputs, func(o outputSpec) bool { return o.name == out.name })
+		if idx < 0 {
+			errs = append(errs, process.AddOutput(plugin.NewOutlet(out.outlet, out.args...), out.options()...))
+		} else if !reflect.DeepEqual(old.outputs[idx], out) {
+			errs = append(errs, process.ReplaceOutput(out.name, plugin.NewOutlet(out.outlet, out.args...), out.options()...))
+		}
+	}
+	p.spec = spec
+	return errors.Join(errs...)
+}
+
+// processorPlugins returns the processors in the order of
+// include, exclude, rename, rate, scale, drop-nan and aggregate.
+// The rename, scale and aggregate can have multiple lines of "<pattern> <values...>".
+func (conf *processConf) processorPlugins() []model.PluginInstance {
+	ret := []model.PluginInstance{}
+	if val, err := conf.GetConfig(CONF_PROC_INCLUDE); err == nil && strings.TrimSpace(val) != "" {
+		ret = append(ret, model.PluginInstance{Plugin: "proc-include", Args: []string{val}})
+	}
+	if val, err := conf.GetConfig(CONF_PROC_EXCLUDE); err == nil && strings.TrimSpace(val) != "" {
+		ret = append(ret, model.PluginInstance{Plugin: "proc-exclude", Args: []string{val}})
+	}
+	ret = append(ret, conf.processorLines(CONF_PROC_RENAME, "proc-rename")...)
+	if val, err := conf.GetConfig(CONF_PROC_RATE); err == nil && strings.TrimSpace(val) != "" {
+		mode, _ := conf.GetConfig(CONF_PROC_RATE_MODE)
+		if mode = strings.TrimSpace(mode); mode == "" {
+			mode = "add"
+		}
+		ret = append(ret, model.PluginInstance{Plugin: "proc-rate", Args: []string{val, mode}})
+	}
+	ret = append(ret, conf.processorLines(CONF_PROC_SCALE, "proc-scale")...)
+	if val, err := conf.GetConfig(CONF_PROC_DROP_NAN); err == nil {
+		if flag, _ := strconv.ParseBool(strings.TrimSpace(val)); flag {
+			ret = append(ret, model.PluginInstance{Plugin: "proc-drop-nan"})
+		}
+	}
+	ret = append(ret, conf.processorLines(CONF_PROC_AGGREGATE, "proc-aggregate")...)
+	return ret
+}
+
+func (conf *processConf) processorLines(key string, name string) []model.PluginInstance {
+	ret := []model.PluginInstance{}
+	val, err := conf.GetConfig(key)
+	if err != nil {
+		return ret
+	}
+	for _, line := range strings.Split(val, "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			ret = append(ret, model.PluginInstance{Plugin: name, Args: fields})
+		}
+	}
+	return ret
+}
+
+// parseTags parses the comma separated "key=value" tags, e.g. "host,site=seoul".
+// The key "host" without value is replaced with the hostname.
+func parseTags(str string) []report.Tag {
+	ret := []report.Tag{}
+	for _, kv := range strings.Split(str, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(kv, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if k == "host" && v == "" {
+			v, _ = os.Hostname()
+		}
+		if k == "" || v == "" {
+			continue
+		}
+		ret = append(ret, report.Tag{Key: k, Value: v})
+	}
+	return ret
+}
+
+// outletFormatArgs returns the options of the outlets how to write the tags.
+func (conf *processConf) outletFormatArgs() []string {
+	ret := []string{}
+	if val, err := conf.GetConfig(CONF_NAME_TEMPLATE); err == nil && strings.TrimSpace(val) != "" {
+		ret = append(ret, "name_template="+strings.TrimSpace(val))
+	}
+	if val, err := conf.GetConfig(CONF_TAGS_COLUMN); err == nil && strings.TrimSpace(val) != "" {
+		ret = append(ret, "tags="+strings.TrimSpace(val))
+	}
+	return ret
+}
+
+const (
+	defaultSpoolMaxSize = 64 * 1024 * 1024
+	defaultSpoolMaxAge  = 24 * time.Hour
+)
+
+// spoolSpec returns the spool of the outlet,
+// that is placed in the sub directory of the spool_dir,
+// "<spool_dir>/<outlet>" or "<spool_dir>/<pipeline>/<outlet>" if it is not the default pipeline.
+func (conf *processConf) spoolSpec(name string, options map[string]string) (spoolSpec, error) {
+	option := func(key string) (string, bool) {
+		if val, ok := options[key]; ok && strings.TrimSpace(val) != "" {
+			return strings.TrimSpace(val), true
+		}
+		if val, err := conf.GetConfig(key); err == nil && strings.TrimSpace(val) != "" {
+			return strings.TrimSpace(val), true
+		}
+		return "", false
+	}
+	ret := spoolSpec{
+		maxSize: defaultSpoolMaxSize,
+		maxAge:  defaultSpoolMaxAge,
+	}
+	// the spool_dir of the output is used as it is
+	if dir, ok := options[CONF_SPOOL_DIR]; ok && strings.TrimSpace(dir) != "" {
+		ret.dir = strings.TrimSpace(dir)
+	} else if dir, err := conf.GetConfig(CONF_SPOOL_DIR); err == nil && strings.TrimSpace(dir) != "" {
+		dir = strings.TrimSpace(dir)
+		if conf.pipeline != DefaultPipeline {
+			dir = filepath.Join(dir, conf.pipeline)
+		}
+		ret.dir = filepath.Join(dir, name)
+	} else {
+		return spoolSpec{}, nil
+	}
+	if val, ok := option(CONF_SPOOL_MAX_SIZE); ok {
+		var err error
+		if ret.maxSize, err = parseSize(val); err != nil {
+			return ret, fmt.Errorf("%s %q is wrong value", CONF_SPOOL_MAX_SIZE, val)
+		}
+	}
+	if val, ok := option(CONF_SPOOL_MAX_AGE); ok {
+		var err error
+		if ret.maxAge, err = time.ParseDuration(val); err != nil {
+			return ret, fmt.Errorf("%s %q is wrong value", CONF_SPOOL_MAX_AGE, val)
+		}
+	}
+	return ret, nil
+}
+
+// parseSize parses the size in bytes, e.g. "1048576", "512KB", "64MB", "1GB"
+func parseSize(str string) (int64, error) {
+	str = strings.ToUpper(strings.TrimSpace(str))
+	unit := int64(1)
+	for _, u := range []struct {
+		suffix string
+		unit   int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(str, u.suffix) {
+			str, unit = strings.TrimSpace(strings.TrimSuffix(str, u.suffix)), u.unit
+			break
+		}
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", str)
+	}
+	return n * unit, nil
+}
+
+func (s *Server) StopProcess(name string) {
+	s.processLock.Lock()
+	defer s.processLock.Unlock()
+	s.stopProcess(name)
+}
+
+func (s *Server) stopProcess(name string) {
+	if p, ok := s.pipelines[name]; ok && p.process.Running() {
+		p.process.Stop()
+	}
+}
+
+func (s *Server) RestartProcess(name string) error {
+	s.processLock.Lock()
+	defer s.processLock.Unlock()
+	s.stopProcess(name)
+	return s.startProcess(name)
+}
+
+// RemoveProcess stops the process of the pipeline and forgets it.
+func (s *Server) RemoveProcess(name string) {
+	s.processLock.Lock()
+	defer s.processLock.Unlock()
+	s.stopProcess(name)
+	delete(s.pipelines, name)
 }
@@ -1,7 +1,11 @@
 package pstag
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -9,10 +13,73 @@ import (
 )
 
 type InputHandler struct {
-	ch      chan<- *report.Report
-	inlet   report.Inlet
-	closeCh chan bool
-	closeWg sync.WaitGroup
+	name     string
+	ch       chan<- *report.Report
+	inlet    report.Inlet
+	interval time.Duration
+	timeout  time.Duration
+	align    bool
+	splay    time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	closeCh  chan bool
+	closeWg  sync.WaitGroup
+
+	statsLock       sync.Mutex
+	runs            int64
+	errors          int64
+	timeouts        int64
+	overruns        int64
+	records         int64
+	lastRecords     int
+	lastDuration    time.Duration
+	lastError       string
+	lastErrorTime   time.Time
+	lastSuccessTime time.Time
+}
+
+type InputOption func(*InputHandler)
+
+// WithInputName sets the name of the input, that identifies the input in the PsTag.
+func WithInputName(name string) InputOption {
+	return func(in *InputHandler) {
+		in.name = name
+	}
+}
+
+// WithInputInterval overrides the collection interval of the PsTag
+// for the inlet.
+func WithInputInterval(interval time.Duration) InputOption {
+	return func(in *InputHandler) {
+		in.interval = interval
+	}
+}
+
+// WithInputTimeout sets the deadline of a collection,
+// the interval of the input is used if not set.
+func WithInputTimeout(timeout time.Duration) InputOption {
+	return func(in *InputHandler) {
+		in.timeout = timeout
+	}
+}
+
+// WithInputAlign fires the input on the boundaries of the interval
+// on the wall-clock, e.g. :00, :10, :20 for 10s, instead of the time it started.
+// The records are stamped with the boundary, so that the inputs
+// of the same interval have the same timestamps.
+func WithInputAlign(align bool) InputOption {
+	return func(in *InputHandler) {
+		in.align = align
+	}
+}
+
+// WithInputSplay delays the aligned input by a random duration up to splay,
+// so that many hosts do not collect and send at the same instant.
+// The records are still stamped with the boundary.
+func WithInputSplay(splay time.Duration) InputOption {
+	return func(in *InputHandler) {
+		in.splay = splay
+	}
 }
 
 func NewInputFunc(ch chan<- *report.Report, inlet report.Inlet) *InputHandler {
@@ -23,50 +90,176 @@ func NewInputFunc(ch chan<- *report.Report, inlet report.Inlet) *InputHandler {
 	}
 }
 
-func (in *InputHandler) Start(interval time.Duration, tagPrefix string) error {
+func (in *InputHandler) Start(interval time.Duration) error {
+	if in.interval > 0 {
+		interval = in.interval
+	}
+	if interval < 1*time.Second {
+		interval = 1 * time.Second
+	}
+	timeout := in.timeout
+	if timeout <= 0 {
+		timeout = interval
+	}
 	if err := in.inlet.Open(); err != nil {
-		slog.Error("failed to open input", "error", err.Error())
+		slog.Error("failed to open input", "input", in.name, "error", err.Error())
 		return err
 	}
+	in.ctx, in.cancel = context.WithCancel(context.Background())
 
-	in.run(time.Now(), tagPrefix)
 	in.closeWg.Add(1)
-	go func() {
-		tick := time.NewTicker(interval)
-	loop:
-		for {
-			select {
-			case ts := <-tick.C:
-				in.run(ts, tagPrefix)
-			case <-in.closeCh:
-				break loop
+	if in.align {
+		go in.alignedLoop(interval, timeout)
+		return nil
+	}
+	in.run(time.Now(), timeout)
+	go in.tickLoop(interval, timeout)
+	return nil
+}
+
+func (in *InputHandler) tickLoop(interval, timeout time.Duration) {
+	defer in.closeWg.Done()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case ts := <-tick.C:
+			// the tick has been waiting for the previous collection
+			// that took longer than the interval, skip it.
+			if late := time.Since(ts); late >= interval {
+				in.overrun(1, late)
+				continue
 			}
+			in.run(ts, timeout)
+		case <-in.closeCh:
+			return
 		}
-		in.closeWg.Done()
-	}()
-	return nil
+	}
+}
+
+func (in *InputHandler) alignedLoop(interval, timeout time.Duration) {
+	defer in.closeWg.Done()
+	var offset time.Duration
+	if in.splay > 0 {
+		offset = time.Duration(rand.Int63n(int64(in.splay)))
+	}
+	next := time.Now().Truncate(interval).Add(interval)
+	timer := time.NewTimer(time.Until(next.Add(offset)))
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			in.run(next, timeout)
+			following := next.Add(interval)
+			// the boundaries that have been passed by the collection are skipped
+			if now := time.Now().Add(-offset); !now.Before(following) {
+				late := now.Sub(following)
+				following = now.Truncate(interval).Add(interval)
+				in.overrun(int64(following.Sub(next)/interval)-1, late)
+			}
+			next = following
+			timer.Reset(time.Until(next.Add(offset)))
+		case <-in.closeCh:
+			return
+		}
+	}
+}
+
+func (in *InputHandler) overrun(skipped int64, late time.Duration) {
+	in.statsLock.Lock()
+	in.overruns += skipped
+	in.statsLock.Unlock()
+	slog.Warn("skip input, overrun", "input", in.name, "skipped", skipped, "late", late)
 }
 
 func (in *InputHandler) Stop() {
+	if in.cancel == nil {
+		// not started, or failed to start
+		return
+	}
+	// cancel the collection in progress
+	in.cancel()
+	in.cancel = nil
 	in.closeCh <- true
 	in.closeWg.Wait()
 
 	if err := in.inlet.Close(); err != nil {
-		slog.Error("failed to close input", "error", err.Error())
+		slog.Error("failed to close input", "input", in.name, "error", err.Error())
 	}
 }
 
-func (in *InputHandler) run(ts time.Time, tagPrefix string) {
-	recs, err := in.inlet.Handle()
+func (in *InputHandler) run(ts time.Time, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(in.ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	recs, err := in.inlet.Handle(ctx)
+	elapsed := time.Since(start)
+	if err != nil && in.ctx.Err() != nil {
+		// stopped
+		return
+	}
+	// the inlets may wrap the error of the ctx without %w
+	timedOut := err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
+	if timedOut {
+		err = fmt.Errorf("timeout after %s", timeout)
+	}
+
+	in.statsLock.Lock()
+	in.runs++
+	in.records += int64(len(recs))
+	in.lastRecords = len(recs)
+	in.lastDuration = elapsed
+	if timedOut {
+		in.timeouts++
+	}
+	if err != nil {
+		in.errors++
+		in.lastError = err.Error()
+		in.lastErrorTime = start
+	} else {
+		in.lastSuccessTime = start
+	}
+	in.statsLock.Unlock()
+
 	if err != nil {
-		slog.Error("failed to get input", "error", err.Error())
+		slog.Error("failed to get input", "input", in.name, "error", err.Error())
 	}
 	if len(recs) > 0 {
-		if tagPrefix != "" {
-			for _, r := range recs {
-				r.Name = tagPrefix + r.Name
-			}
+		select {
+		case in.ch <- &report.Report{Ts: ts, Records: recs}:
+		case <-in.ctx.Done():
 		}
-		in.ch <- &report.Report{Ts: ts, Records: recs}
+	}
+}
+
+type InputStats struct {
+	Name            string    `json:"name"`
+	Runs            int64     `json:"runs"`
+	Errors          int64     `json:"errors"`
+	Timeouts        int64     `json:"timeouts"`
+	Overruns        int64     `json:"overruns"`
+	Records         int64     `json:"records"`
+	LastRecords     int       `json:"last_records"`
+	LastDuration    float64   `json:"last_duration_ms"`
+	LastError       string    `json:"last_error,omitempty"`
+	LastErrorTime   time.Time `json:"last_error_time"`
+	LastSuccessTime time.Time `json:"last_success_time"`
+}
+
+func (in *InputHandler) Stats() InputStats {
+	in.statsLock.Lock()
+	defer in.statsLock.Unlock()
+	return InputStats{
+		Name:            in.name,
+		Runs:            in.runs,
+		Errors:          in.errors,
+		Timeouts:        in.timeouts,
+		Overruns:        in.overruns,
+		Records:         in.records,
+		LastRecords:     in.lastRecords,
+		LastDuration:    float64(in.lastDuration) / float64(time.Millisecond),
+		LastError:       in.lastError,
+		LastErrorTime:   in.lastErrorTime,
+		LastSuccessTime: in.lastSuccessTime,
 	}
 }
@@ -0,0 +1,77 @@
+package pstag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/stretchr/testify/require"
+)
+
+// hangInlet blocks until the ctx is done.
+type hangInlet struct{}
+
+func (hi *hangInlet) Open() error  { return nil }
+func (hi *hangInlet) Close() error { return nil }
+func (hi *hangInlet) Handle(ctx context.Context) ([]*report.Record, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestInputTimeout(t *testing.T) {
+	ch := make(chan *report.Report, 10)
+	in := NewInputFunc(ch, &hangInlet{})
+	WithInputTimeout(50 * time.Millisecond)(in)
+	require.NoError(t, in.Start(time.Second))
+
+	stats := in.Stats()
+	require.Equal(t, int64(1), stats.Timeouts)
+	require.Equal(t, int64(1), stats.Errors)
+	require.Contains(t, stats.LastError, "timeout")
+
+	done := make(chan struct{})
+	go func() {
+		in.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("stop blocked")
+	}
+	require.Empty(t, ch)
+}
+
+type constInlet struct{}
+
+func (ci *constInlet) Open() error  { return nil }
+func (ci *constInlet) Close() error { return nil }
+func (ci *constInlet) Handle(ctx context.Context) ([]*report.Record, error) {
+	return []*report.Record{{Name: "const", Value: 1}}, nil
+}
+
+func TestInputAlign(t *testing.T) {
+	ch := make(chan *report.Report, 10)
+	in := NewInputFunc(ch, &constInlet{})
+	WithInputAlign(true)(in)
+	WithInputSplay(100 * time.Millisecond)(in)
+	require.NoError(t, in.Start(time.Second))
+	defer in.Stop()
+
+	// no immediate report, it waits for the boundary
+	require.Empty(t, ch)
+	select {
+	case rpt := <-ch:
+		require.True(t, rpt.Ts.Equal(rpt.Ts.Truncate(time.Second)), "ts %s", rpt.Ts)
+		require.False(t, rpt.Ts.After(time.Now()))
+	case <-time.After(3 * time.Second):
+		t.Fatal("no report")
+	}
+}
+
+func TestInputStopNotStarted(t *testing.T) {
+	in := NewInputFunc(make(chan *report.Report, 1), &constInlet{})
+	in.Stop()
+}
@@ -1,7 +1,9 @@
 package pstag
 
 import (
+	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
@@ -9,6 +11,7 @@ import (
 )
 
 type OutputHandler struct {
+	name    string
 	ch      chan *report.Report
 	outlet  report.Outlet
 	closeCh chan bool
@@ -16,21 +19,138 @@ type OutputHandler struct {
 
 	buffer        []*report.Report
 	bufferTimeout time.Duration
+
+	spool        *Spool
+	spoolDir     string
+	spoolMaxSize int64
+	spoolMaxAge  time.Duration
+
+	retry   RetryPolicy
+	breaker *CircuitBreaker
+
+	queueSize int
+	overflow  OverflowPolicy
+
+	healthLock      sync.Mutex
+	lastError       string
+	lastErrorTime   time.Time
+	lastSuccessTime time.Time
+	lastDuration    time.Duration
+	batches         int64
+	records         int64
+	errors          int64
+	dropped         int64
+	// buffered is the number of reports in the buffer, the buffer itself
+	// belongs to the goroutine of the output.
+	buffered int
+}
+
+type OutputOption func(*OutputHandler)
+
+// WithOutputName sets the name of the output that is used in the logs and the health.
+func WithOutputName(name string) OutputOption {
+	return func(out *OutputHandler) {
+		out.name = name
+	}
+}
+
+// WithRetry sets the retry policy of the outlet, DefaultRetryPolicy is used if not set.
+func WithRetry(policy RetryPolicy) OutputOption {
+	return func(out *OutputHandler) {
+		out.retry = policy
+	}
+}
+
+// WithCircuitBreaker opens the circuit after threshold failures in a row,
+// and stops calling the outlet for the cooldown.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) OutputOption {
+	return func(out *OutputHandler) {
+		out.breaker = NewCircuitBreaker(threshold, cooldown)
+	}
+}
+
+// WithSpool keeps the batches that failed to be delivered in the dir,
+// and replays them once the outlet succeeds again.
+// The oldest batches are discarded when the spool exceeds maxSize bytes
+// or they are older than maxAge. Zero means no limit.
+func WithSpool(dir string, maxSize int64, maxAge time.Duration) OutputOption {
+	return func(out *OutputHandler) {
+		out.spoolDir = dir
+		out.spoolMaxSize = maxSize
+		out.spoolMaxAge = maxAge
+	}
+}
+
+// OverflowPolicy decides what to do with a report
+// when the queue of the output is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits until the queue has room, it stalls the other outputs and the inputs.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest discards the oldest report in the queue.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDropNewest discards the report that is being queued.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+)
+
+func ParseOverflowPolicy(str string) (OverflowPolicy, error) {
+	switch p := OverflowPolicy(strings.ToLower(strings.TrimSpace(str))); p {
+	case OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown overflow policy %q", str)
+}
+
+// DefaultQueueSize is the number of reports that an output can hold
+// while the outlet is slow.
+const DefaultQueueSize = 100
+
+// WithQueue sets the size of the queue and the policy when it is full,
+// the default is DefaultQueueSize and OverflowDropOldest.
+func WithQueue(size int, policy OverflowPolicy) OutputOption {
+	return func(out *OutputHandler) {
+		out.queueSize = size
+		out.overflow = policy
+	}
 }
 
-func NewOutputHandler(outlet report.Outlet, interval time.Duration) *OutputHandler {
-	return &OutputHandler{
-		ch:            make(chan *report.Report, 1),
+// spoolReplayBatches is the max number of spooled batches
+// that are replayed in a flush.
+const spoolReplayBatches = 16
+
+func NewOutputHandler(outlet report.Outlet, interval time.Duration, opts ...OutputOption) *OutputHandler {
+	ret := &OutputHandler{
 		outlet:        outlet,
 		closeCh:       make(chan bool),
 		buffer:        make([]*report.Report, 0, 256),
 		bufferTimeout: interval,
+		retry:         DefaultRetryPolicy,
+		breaker:       NewCircuitBreaker(5, 30*time.Second),
+		queueSize:     DefaultQueueSize,
+		overflow:      OverflowDropOldest,
+	}
+	for _, opt := range opts {
+		opt(ret)
 	}
+	if ret.queueSize < 1 {
+		ret.queueSize = 1
+	}
+	ret.ch = make(chan *report.Report, ret.queueSize)
+	return ret
 }
 
 func (out *OutputHandler) Start() error {
+	if out.spoolDir != "" {
+		if sp, err := NewSpool(out.spoolDir, out.spoolMaxSize, out.spoolMaxAge); err != nil {
+			slog.Error("failed to open spool", "dir", out.spoolDir, "error", err.Error())
+			return err
+		} else {
+			out.spool = sp
+		}
+	}
 	if err := out.outlet.Open(); err != nil {
-		slog.Error("failed to open output", "error", err.Error())
+		slog.Error("failed to open output", "output", out.name, "error", err.Error())
 		return err
 	}
 
@@ -38,43 +158,238 @@ func (out *OutputHandler) Start() error {
 
 	out.closeWg.Add(1)
 	go func() {
-	loop:
+		defer out.closeWg.Done()
+		defer tick.Stop()
 		for {
 			select {
 			case r := <-out.ch:
 				out.buffer = append(out.buffer, r)
+				out.setBuffered(len(out.buffer))
 			case <-tick.C:
 				out.flush()
 			case <-out.closeCh:
+				// the reports left in the queue go with the last flush
+				for drained := false; !drained; {
+					select {
+					case r := <-out.ch:
+						out.buffer = append(out.buffer, r)
+					default:
+						drained = true
+					}
+				}
 				out.flush()
-				break loop
+				return
 			}
 		}
-		out.closeWg.Done()
 	}()
 	return nil
 }
 
 func (out *OutputHandler) flush() {
+	replayed := out.replay()
 	if len(out.buffer) == 0 {
 		return
 	}
-	if err := out.outlet.Handle(out.buffer); err != nil {
-		slog.Error("failed to output flush", "error", err.Error())
+	if !replayed {
+		// keep the order, the buffer goes behind the spooled batches
+		out.spoolBuffer()
+	} else if err := out.handle(out.buffer); err != nil {
+		if err != ErrCircuitOpen {
+			slog.Error("failed to output flush", "output", out.name, "error", err.Error())
+		}
+		out.spoolBuffer()
 	}
 	out.buffer = out.buffer[:0]
+	out.setBuffered(0)
+}
+
+func (out *OutputHandler) setBuffered(n int) {
+	out.healthLock.Lock()
+	out.buffered = n
+	out.healthLock.Unlock()
+}
+
+// replay sends the spooled batches to the outlet in order.
+// It returns true if the spool has been drained.
+func (out *OutputHandler) replay() bool {
+	if out.spool == nil {
+		return true
+	}
+	for i := 0; i < spoolReplayBatches; i++ {
+		name, rpts, err := out.spool.Peek()
+		if name == "" {
+			return true
+		}
+		if err != nil {
+			slog.Error("failed to read spool", "file", name, "error", err.Error())
+			out.spool.Remove(name)
+			continue
+		}
+		if err := out.handle(rpts); err != nil {
+			if err != ErrCircuitOpen {
+				slog.Error("failed to output replay", "output", out.name, "error", err.Error())
+			}
+			return false
+		}
+		out.spool.Remove(name)
+	}
+	return out.spool.Len() == 0
+}
+
+// handle calls the outlet with retries unless the circuit is open.
+func (out *OutputHandler) handle(rpts []*report.Report) error {
+	if !out.breaker.Allow() {
+		return ErrCircuitOpen
+	}
+	var err error
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		err = out.outlet.Handle(rpts)
+		out.healthLock.Lock()
+		out.lastDuration = time.Since(start)
+		out.healthLock.Unlock()
+		if err == nil {
+			out.breaker.Success()
+			records := 0
+			for _, r := range rpts {
+				records += len(r.Records)
+			}
+			out.healthLock.Lock()
+			out.lastSuccessTime = time.Now()
+			out.batches++
+			out.records += int64(records)
+			out.healthLock.Unlock()
+			return nil
+		}
+		// a trial call of the half-open circuit is not retried
+		if attempt >= out.retry.MaxRetries || out.breaker.State() == BreakerHalfOpen {
+			break
+		}
+		select {
+		case <-time.After(out.retry.Backoff(attempt)):
+			continue
+		case <-out.closeCh:
+		}
+		break
+	}
+	out.breaker.Failure()
+	out.healthLock.Lock()
+	out.lastError = err.Error()
+	out.lastErrorTime = time.Now()
+	out.errors++
+	out.healthLock.Unlock()
+	return err
+}
+
+func (out *OutputHandler) spoolBuffer() {
+	if out.spool == nil {
+		return
+	}
+	if err := out.spool.Push(out.buffer); err != nil {
+		slog.Error("failed to write spool", "error", err.Error())
+	}
 }
 
 func (out *OutputHandler) Stop() {
-	out.closeCh <- true
+	close(out.closeCh)
 	out.closeWg.Wait()
 	close(out.ch)
 
 	if err := out.outlet.Close(); err != nil {
-		slog.Error("failed to open output", "error", err.Error())
+		slog.Error("failed to close output", "output", out.name, "error", err.Error())
+	}
+}
+
+// Enqueue puts the report into the queue of the output,
+// it returns false if a report has been dropped by the overflow policy.
+func (out *OutputHandler) Enqueue(r *report.Report) bool {
+	switch out.overflow {
+	case OverflowBlock:
+		out.ch <- r
+		return true
+	case OverflowDropNewest:
+		select {
+		case out.ch <- r:
+			return true
+		default:
+			out.drop()
+			return false
+		}
+	default:
+		for dropped := false; ; {
+			select {
+			case out.ch <- r:
+				return !dropped
+			default:
+			}
+			select {
+			case <-out.ch:
+				out.drop()
+				dropped = true
+			default:
+			}
+		}
 	}
 }
 
-func (out *OutputHandler) Sink() chan<- *report.Report {
-	return out.ch
+func (out *OutputHandler) drop() {
+	out.healthLock.Lock()
+	out.dropped++
+	dropped := out.dropped
+	out.healthLock.Unlock()
+	// not to flood the log while the outlet is stalled
+	if dropped&(dropped-1) == 0 {
+		slog.Warn("output queue overflow", "output", out.name, "policy", out.overflow, "dropped", dropped)
+	}
+}
+
+type OutputHealth struct {
+	Name                string       `json:"name"`
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastError           string       `json:"last_error,omitempty"`
+	LastErrorTime       time.Time    `json:"last_error_time"`
+	LastSuccessTime     time.Time    `json:"last_success_time"`
+}
+
+func (out *OutputHandler) Health() OutputHealth {
+	out.healthLock.Lock()
+	defer out.healthLock.Unlock()
+	return OutputHealth{
+		Name:                out.name,
+		State:               out.breaker.State(),
+		ConsecutiveFailures: out.breaker.Failures(),
+		LastError:           out.lastError,
+		LastErrorTime:       out.lastErrorTime,
+		LastSuccessTime:     out.lastSuccessTime,
+	}
+}
+
+type OutputStats struct {
+	OutputHealth
+	Batches      int64   `json:"batches"`
+	Records      int64   `json:"records"`
+	Errors       int64   `json:"errors"`
+	Dropped      int64   `json:"dropped"`
+	LastDuration float64 `json:"last_duration_ms"`
+	QueueDepth   int     `json:"queue_depth"`
+	QueueSize    int     `json:"queue_size"`
+	Spooled      int     `json:"spooled"`
+}
+
+func (out *OutputHandler) Stats() OutputStats {
+	ret := OutputStats{OutputHealth: out.Health()}
+	out.healthLock.Lock()
+	ret.Batches = out.batches
+	ret.Records = out.records
+	ret.Errors = out.errors
+	ret.Dropped = out.dropped
+	ret.QueueSize = out.queueSize
+	ret.LastDuration = float64(out.lastDuration) / float64(time.Millisecond)
+	ret.QueueDepth = out.buffered + len(out.ch)
+	out.healthLock.Unlock()
+	if out.spool != nil {
+		ret.Spooled = out.spool.Len()
+	}
+	return ret
 }
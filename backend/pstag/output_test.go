@@ -0,0 +1,101 @@
+package pstag
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/stretchr/testify/require"
+)
+
+type failOutlet struct {
+	fails int
+	calls int
+	recvd []*report.Report
+}
+
+func (fo *failOutlet) Open() error  { return nil }
+func (fo *failOutlet) Close() error { return nil }
+func (fo *failOutlet) Handle(r []*report.Report) error {
+	fo.calls++
+	if fo.calls <= fo.fails {
+		return errors.New("failure")
+	}
+	fo.recvd = append(fo.recvd, r...)
+	return nil
+}
+
+func TestOutputRetry(t *testing.T) {
+	outlet := &failOutlet{fails: 2}
+	out := NewOutputHandler(outlet, time.Second,
+		WithRetry(RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}))
+	require.NoError(t, out.handle([]*report.Report{{Ts: time.Now(), Records: []*report.Record{{Name: "cpu.percent"}}}}))
+	require.Equal(t, 3, outlet.calls)
+	require.Len(t, outlet.recvd, 1)
+	require.Equal(t, BreakerClosed, out.Health().State)
+
+	stats := out.Stats()
+	require.Equal(t, int64(1), stats.Batches)
+	require.Equal(t, int64(1), stats.Records)
+	require.Equal(t, int64(0), stats.Errors)
+}
+
+func TestOutputCircuitBreaker(t *testing.T) {
+	outlet := &failOutlet{fails: 100}
+	out := NewOutputHandler(outlet, time.Second,
+		WithRetry(RetryPolicy{MaxRetries: 0}),
+		WithCircuitBreaker(2, 50*time.Millisecond))
+	require.Error(t, out.handle(nil))
+	require.Error(t, out.handle(nil))
+	require.Equal(t, BreakerOpen, out.Health().State)
+	require.Equal(t, ErrCircuitOpen, out.handle(nil))
+	require.Equal(t, 2, outlet.calls)
+	require.Equal(t, int64(2), out.Stats().Errors)
+
+	// half-open trial succeeds
+	time.Sleep(60 * time.Millisecond)
+	outlet.fails = 0
+	require.NoError(t, out.handle(nil))
+	require.Equal(t, BreakerClosed, out.Health().State)
+}
+
+func TestOutputStopDrain(t *testing.T) {
+	outlet := &failOutlet{}
+	out := NewOutputHandler(outlet, time.Hour, WithQueue(100, OverflowBlock))
+	require.NoError(t, out.Start())
+	for i := 0; i < 50; i++ {
+		require.True(t, out.Enqueue(&report.Report{Ts: time.Unix(int64(i), 0)}))
+	}
+	out.Stop()
+	require.Len(t, outlet.recvd, 50)
+	for i, r := range outlet.recvd {
+		require.Equal(t, int64(i), r.Ts.Unix())
+	}
+}
+
+func TestOutputOverflow(t *testing.T) {
+	rpt := func(n int) *report.Report {
+		return &report.Report{Ts: time.Unix(int64(n), 0)}
+	}
+	// not started, nothing drains the queue
+	out := NewOutputHandler(&failOutlet{}, time.Second, WithQueue(2, OverflowDropNewest))
+	require.True(t, out.Enqueue(rpt(1)))
+	require.True(t, out.Enqueue(rpt(2)))
+	require.False(t, out.Enqueue(rpt(3)))
+	require.Equal(t, int64(1), out.Stats().Dropped)
+	require.Equal(t, int64(1), (<-out.ch).Ts.Unix())
+
+	out = NewOutputHandler(&failOutlet{}, time.Second, WithQueue(2, OverflowDropOldest))
+	for i := 1; i <= 5; i++ {
+		out.Enqueue(rpt(i))
+	}
+	require.Equal(t, int64(3), out.Stats().Dropped)
+	require.Equal(t, 2, out.Stats().QueueDepth)
+	require.Equal(t, int64(4), (<-out.ch).Ts.Unix())
+	require.Equal(t, int64(5), (<-out.ch).Ts.Unix())
+
+	_, err := ParseOverflowPolicy("drop-all")
+	require.Error(t, err)
+}
@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+)
+
+const defaultCgroupRoot = "/sys/fs/cgroup"
+
+// cgroupDirs returns the cgroups (v2) under the root that match the glob patterns.
+func cgroupDirs(root string, patterns []string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("%s is not the cgroup v2 hierarchy", root)
+	}
+	found := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if st, err := os.Stat(m); err == nil && st.IsDir() {
+				found[m] = true
+			}
+		}
+	}
+	ret := make([]string, 0, len(found))
+	for dir := range found {
+		ret = append(ret, dir)
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// cgroupName returns the path of the cgroup relative to the root, "/" for the root.
+func cgroupName(root string, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return "/"
+	}
+	return rel
+}
+
+type cgroupCPUSample struct {
+	ts        time.Time
+	usage     float64
+	periods   float64
+	throttled float64
+}
+
+// CgroupInput reports the resources of the cgroups (v2) that match the glob patterns.
+//
+//	args[0]      glob patterns of the cgroups relative to the root, comma(,) separated,
+//	             (e.g. system.slice/machbase-neo.service,system.slice/docker-*.scope)
+//	root=<path>  the mount point of the cgroup2 file system, default /sys/fs/cgroup
+//
+// The records are tagged with cgroup=<path>. The files of the controllers
+// that are not enabled in the cgroup are skipped.
+func CgroupInput(args []string) func(context.Context) ([]*report.Record, error) {
+	args, opts := parseOptions(args)
+	patterns := splitPatterns(args)
+	root := defaultCgroupRoot
+	if v := strings.TrimSpace(opts["root"]); v != "" {
+		root = v
+	}
+
+	var lock sync.Mutex
+	prev := map[string]cgroupCPUSample{}
+
+	return func(ctx context.Context) ([]*report.Record, error) {
+		dirs, err := cgroupDirs(root, patterns)
+		if err != nil {
+			return nil, fmt.Errorf("inlet cgroup, %s", err)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+
+		now := time.Now()
+		ret := []*report.Record{}
+		seen := map[string]bool{}
+		for _, dir := range dirs {
+			name := cgroupName(root, dir)
+			seen[name] = true
+			tags := []report.Tag{{Key: "cgroup", Value: name}}
+			add := func(field string, value float64, precision int) {
+				ret = append(ret, &report.Record{Name: "cgroup." + field, Value: value, Precision: precision, Tags: tags})
+			}
+
+			if st, err := readFlatKeyed(filepath.Join(dir, "cpu.stat")); err == nil {
+				add("cpu_usage_usec", st["usage_usec"], 0)
+				add("cpu_user_usec", st["user_usec"], 0)
+				add("cpu_system_usec", st["system_usec"], 0)
+				if _, ok := st["nr_periods"]; ok {
+					add("cpu_nr_periods", st["nr_periods"], 0)
+					add("cpu_nr_throttled", st["nr_throttled"], 0)
+					add("cpu_throttled_usec", st["throttled_usec"], 0)
+				}
+				cur := cgroupCPUSample{ts: now, usage: st["usage_usec"], periods: st["nr_periods"], throttled: st["nr_throttled"]}
+				if last, ok := prev[name]; ok {
+					if rate, ok := counterRate(rateSample{ts: last.ts, value: last.usage}, rateSample{ts: cur.ts, value: cur.usage}); ok {
+						// usec per second to percent, 100 is a core
+						add("cpu_percent", rate/1e4, 1)
+					}
+					if periods := cur.periods - last.periods; periods > 0 {
+						add("cpu_throttled_percent", (cur.throttled-last.throttled)/periods*100, 1)
+					}
+				}
+				prev[name] = cur
+			}
+			if quota, period, ok := readCPUMax(filepath.Join(dir, "cpu.max")); ok && period > 0 {
+				add("cpu_limit", quota/period, 2)
+			}
+
+			if v, _, err := readValue(filepath.Join(dir, "memory.current")); err == nil {
+				add("memory_current", v, 0)
+			}
+			if v, ok, err := readValue(filepath.Join(dir, "memory.max")); err == nil && ok {
+				add("memory_max", v, 0)
+			}
+			if v, ok, err := readValue(filepath.Join(dir, "memory.high")); err == nil && ok {
+				add("memory_high", v, 0)
+			}
+			if ev, err := readFlatKeyed(filepath.Join(dir, "memory.events")); err == nil {
+				add("memory_events_high", ev["high"], 0)
+				add("memory_events_max", ev["max"], 0)
+				add("memory_oom", ev["oom"], 0)
+				add("memory_oom_kill", ev["oom_kill"], 0)
+			}
+
+			if io, err := readIOStat(filepath.Join(dir, "io.stat")); err == nil {
+				add("io_rbytes", io["rbytes"], 0)
+				add("io_wbytes", io["wbytes"], 0)
+				add("io_rios", io["rios"], 0)
+				add("io_wios", io["wios"], 0)
+			}
+
+			if v, _, err := readValue(filepath.Join(dir, "pids.current")); err == nil {
+				add("pids_current", v, 0)
+			}
+			if v, ok, err := readValue(filepath.Join(dir, "pids.max")); err == nil && ok {
+				add("pids_max", v, 0)
+			}
+		}
+		for name := range prev {
+			if !seen[name] {
+				delete(prev, name)
+			}
+		}
+		return ret, nil
+	}
+}
+
+// readCPUMax reads cpu.max of "<quota> <period>", the quota is "max" for no limit.
+func readCPUMax(path string) (float64, float64, bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0, 0, false
+	}
+	quota, err1 := strconv.ParseFloat(fields[0], 64)
+	period, err2 := strconv.ParseFloat(fields[1], 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return quota, period, true
+}
+
+// readIOStat reads io.stat, the values of the devices are summed up.
+//
+//	8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+func readIOStat(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ret := map[string]float64{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		for _, field := range fields[min(1, len(fields)):] {
+			if k, v, ok := strings.Cut(field, "="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					ret[k] += f
+				}
+			}
+		}
+	}
+	return ret, sc.Err()
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
+
+func TestCgroupInput(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"cgroup.controllers": "cpu memory io pids\n"})
+	neo := filepath.Join(root, "system.slice", "machbase-neo.service")
+	writeFiles(t, neo, map[string]string{
+		"cpu.stat":       "usage_usec 1000000\nuser_usec 600000\nsystem_usec 400000\nnr_periods 10\nnr_throttled 1\nthrottled_usec 5000\n",
+		"cpu.max":        "200000 100000\n",
+		"memory.current": "104857600\n",
+		"memory.max":     "max\n",
+		"memory.high":    "209715200\n",
+		"memory.events":  "low 0\nhigh 3\nmax 0\noom 1\noom_kill 1\n",
+		"io.stat":        "8:0 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n",
+		"pids.current":   "12\n",
+		"pids.max":       "max\n",
+	})
+	writeFiles(t, filepath.Join(root, "user.slice"), map[string]string{"pids.current": "3\n"})
+
+	input := CgroupInput([]string{"system.slice/*.service", "root=" + root})
+	ret, err := input(context.Background())
+	require.NoError(t, err)
+	values := map[string]float64{}
+	for _, r := range ret {
+		require.Equal(t, "system.slice/machbase-neo.service", r.Tag("cgroup"))
+		values[r.Name] = r.Value
+	}
+	require.Equal(t, 2.0, values["cgroup.cpu_limit"])
+	require.Equal(t, 104857600.0, values["cgroup.memory_current"])
+	require.NotContains(t, values, "cgroup.memory_max")
+	require.Equal(t, 209715200.0, values["cgroup.memory_high"])
+	require.Equal(t, 1.0, values["cgroup.memory_oom_kill"])
+	require.Equal(t, 400.0, values["cgroup.io_wbytes"])
+	require.Equal(t, 12.0, values["cgroup.pids_current"])
+	require.NotContains(t, values, "cgroup.cpu_percent")
+
+	writeFiles(t, neo, map[string]string{
+		"cpu.stat": "usage_usec 2000000\nuser_usec 1200000\nsystem_usec 800000\nnr_periods 20\nnr_throttled 6\nthrottled_usec 9000\n",
+	})
+	ret, err = input(context.Background())
+	require.NoError(t, err)
+	for _, r := range ret {
+		values[r.Name] = r.Value
+	}
+	require.Greater(t, values["cgroup.cpu_percent"], 0.0)
+	require.Equal(t, 50.0, values["cgroup.cpu_throttled_percent"])
+
+	_, err = CgroupInput([]string{"*", "root=" + t.TempDir()})(context.Background())
+	require.Error(t, err)
+}
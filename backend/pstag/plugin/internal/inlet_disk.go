@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// diskFilter selects the partitions by the mount point and the file system type.
+type diskFilter struct {
+	mountpoints    []string
+	excludes       []string
+	fstypes        []string
+	excludeFstypes []string
+}
+
+func (f *diskFilter) match(p disk.PartitionStat) bool {
+	if !slices.Contains(f.mountpoints, "all") && !matchPatterns(f.mountpoints, p.Mountpoint) {
+		return false
+	}
+	if matchPatterns(f.excludes, p.Mountpoint) {
+		return false
+	}
+	if len(f.fstypes) > 0 && !slices.Contains(f.fstypes, p.Fstype) {
+		return false
+	}
+	return !slices.Contains(f.excludeFstypes, p.Fstype)
+}
+
+// filtered returns true if any of the filters is given,
+// then the pseudo file systems like tmpfs are listed to be filtered.
+func (f *diskFilter) filtered() bool {
+	return len(f.excludes) > 0 || len(f.fstypes) > 0 || len(f.excludeFstypes) > 0
+}
+
+// the sources of DiskInput, they are replaced in the tests
+var (
+	diskPartitions = disk.PartitionsWithContext
+	diskUsage      = disk.UsageWithContext
+)
+
+// DiskInput reports the disk usage of the mount points.
+//
+//	args[0]                  glob patterns of the mount points, comma(,) separated,
+//	                         'all' for all mount points (e.g. /,/mnt/*)
+//	exclude=<glob>           glob patterns of the mount points to exclude (e.g. /snap/*,/run/*)
+//	fstypes=<types>          the file system types to include (e.g. ext4,xfs)
+//	exclude_fstypes=<types>  the file system types to exclude (e.g. tmpfs,devtmpfs,overlay,squashfs)
+//
+// The partitions are listed at every collection, so the mount points
+// that appear after the start are reported as they match.
+func DiskInput(args []string) func(context.Context) ([]*report.Record, error) {
+	args, opts := parseOptions(args)
+	filter := &diskFilter{
+		mountpoints:    splitPatterns(args),
+		excludes:       splitPatterns([]string{opts["exclude"]}),
+		fstypes:        splitPatterns([]string{opts["fstypes"]}),
+		excludeFstypes: splitPatterns([]string{opts["exclude_fstypes"]}),
+	}
+	// the pseudo file systems like tmpfs are listed only if they are filtered
+	allPartitions := filter.filtered()
+
+	return func(ctx context.Context) ([]*report.Record, error) {
+		stat, err := diskPartitions(ctx, allPartitions)
+		if err != nil {
+			return nil, fmt.Errorf("inlet disk, %s", err)
+		}
+		ret := []*report.Record{}
+		errs := []error{}
+		seen := map[string]bool{}
+		for _, v := range stat {
+			// the same mount point can be listed several times, e.g. bind mounts
+			if seen[v.Mountpoint] || !filter.match(v) {
+				continue
+			}
+			seen[v.Mountpoint] = true
+			usage, err := diskUsage(ctx, v.Mountpoint)
+			if err != nil {
+				// the mount point may have gone, report the others
+				errs = append(errs, err)
+				continue
+			}
+			tags := []report.Tag{{Key: "mountpoint", Value: v.Mountpoint}}
+			add := func(name string, value float64, precision int) {
+				ret = append(ret, &report.Record{Name: "disk." + name, Value: value, Precision: precision, Tags: tags})
+			}
+			add("total", float64(usage.Total), 0)
+			add("free", float64(usage.Free), 0)
+			add("used", float64(usage.Used), 0)
+			add("used_percent", usage.UsedPercent, 1)
+			if runtime.GOOS != "windows" {
+				add("inodes_total", float64(usage.InodesTotal), 0)
+				add("inodes_free", float64(usage.InodesFree), 0)
+				add("inodes_used", float64(usage.InodesUsed), 0)
+				add("inodes_used_percent", usage.InodesUsedPercent, 1)
+			}
+		}
+		if len(ret) == 0 && len(errs) > 0 {
+			return nil, fmt.Errorf("inlet disk, %s", errors.Join(errs...))
+		}
+		return ret, nil
+	}
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskFilter(t *testing.T) {
+	root := disk.PartitionStat{Mountpoint: "/", Fstype: "ext4"}
+	data := disk.PartitionStat{Mountpoint: "/mnt/data", Fstype: "xfs"}
+	snap := disk.PartitionStat{Mountpoint: "/snap/core/123", Fstype: "squashfs"}
+	run := disk.PartitionStat{Mountpoint: "/run", Fstype: "tmpfs"}
+
+	f := &diskFilter{mountpoints: []string{"all"}}
+	require.True(t, f.match(root))
+	require.True(t, f.match(snap))
+
+	f = &diskFilter{mountpoints: []string{"/", "/mnt/*"}}
+	require.True(t, f.match(root))
+	require.True(t, f.match(data))
+	require.False(t, f.match(snap))
+
+	f = &diskFilter{mountpoints: []string{"all"}, excludes: []string{"/snap/*/*"}, excludeFstypes: []string{"tmpfs"}}
+	require.True(t, f.match(data))
+	require.False(t, f.match(snap))
+	require.False(t, f.match(run))
+
+	f = &diskFilter{mountpoints: []string{"all"}, fstypes: []string{"ext4", "tmpfs"}}
+	require.True(t, f.match(root))
+	require.True(t, f.match(run))
+	require.False(t, f.match(data))
+}
+
+func TestDiskInput(t *testing.T) {
+	origPartitions, origUsage := diskPartitions, diskUsage
+	defer func() { diskPartitions, diskUsage = origPartitions, origUsage }()
+	diskPartitions = func(_ context.Context, all bool) ([]disk.PartitionStat, error) {
+		ret := []disk.PartitionStat{{Mountpoint: "/", Fstype: "ext4"}}
+		if all {
+			ret = append(ret,
+				disk.PartitionStat{Mountpoint: "/run", Fstype: "tmpfs"},
+				disk.PartitionStat{Mountpoint: "/var/lib/docker/overlay2/merged", Fstype: "overlay"})
+		}
+		return ret, nil
+	}
+	diskUsage = func(_ context.Context, path string) (*disk.UsageStat, error) {
+		return &disk.UsageStat{Path: path, Total: 100, Used: 40, Free: 60, UsedPercent: 40}, nil
+	}
+	mountpoints := func(args ...string) []string {
+		ret, err := DiskInput(args)(context.Background())
+		require.NoError(t, err)
+		seen := []string{}
+		for _, r := range ret {
+			if r.Name == "disk.used" {
+				seen = append(seen, r.Tag("mountpoint"))
+			}
+		}
+		return seen
+	}
+
+	require.Equal(t, []string{"/"}, mountpoints("all"))
+	// the pseudo file systems are listed to be filtered
+	require.Equal(t, []string{"/", "/var/lib/docker/overlay2/merged"}, mountpoints("all", "exclude_fstypes=tmpfs"))
+	require.Equal(t, []string{"/"}, mountpoints("all", "exclude_fstypes=tmpfs,overlay"))
+	require.Equal(t, []string{"/run"}, mountpoints("all", "fstypes=tmpfs"))
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+type diskioSample struct {
+	ts   time.Time
+	stat disk.IOCountersStat
+}
+
+// DiskioInput reports the disk I/O of the devices.
+//
+//	args[0]          glob patterns of the devices, comma(,) separated (e.g. sda,sdb,nvme*)
+//	exclude=<glob>   glob patterns of the devices to exclude, comma(,) separated,
+//	                 for the partitions and the virtual devices (e.g. loop*,dm-*,ram*,sd?[0-9]*)
+//
+// The cumulative counters are reported as they are, and from the second collection
+// the per-interval values like iostat -x, iops, bytes per second, await and util.
+func DiskioInput(args []string) func(context.Context) ([]*report.Record, error) {
+	args, opts := parseOptions(args)
+	devPatterns := splitPatterns(args)
+	excludes := splitPatterns([]string{opts["exclude"]})
+
+	var lock sync.Mutex
+	prev := map[string]diskioSample{}
+
+	return func(ctx context.Context) ([]*report.Record, error) {
+		stat, err := disk.IOCountersWithContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("inlet diskio, %s", err)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+
+		now := time.Now()
+		ret := []*report.Record{}
+		for _, v := range stat {
+			if !matchPatterns(devPatterns, v.Name) || matchPatterns(excludes, v.Name) {
+				continue
+			}
+			tags := []report.Tag{{Key: "device", Value: v.Name}}
+			add := func(name string, value float64, precision int) {
+				ret = append(ret, &report.Record{Name: "diskio." + name, Value: value, Precision: precision, Tags: tags})
+			}
+			add("read_bytes", float64(v.ReadBytes), 0)
+			add("read_time", float64(v.ReadTime), 0)
+			add("write_bytes", float64(v.WriteBytes), 0)
+			add("write_time", float64(v.WriteTime), 0)
+			add("read_count", float64(v.ReadCount), 0)
+			add("write_count", float64(v.WriteCount), 0)
+			add("merged_read_count", float64(v.MergedReadCount), 0)
+			add("merged_write_count", float64(v.MergedWriteCount), 0)
+			add("io_time", float64(v.IoTime), 0)
+			add("weighted_io", float64(v.WeightedIO), 0)
+			add("iops_in_progress", float64(v.IopsInProgress), 0)
+
+			cur := diskioSample{ts: now, stat: v}
+			if last, ok := prev[v.Name]; ok {
+				ret = append(ret, diskioDerived(last, cur, tags)...)
+			}
+			prev[v.Name] = cur
+		}
+		for name, s := range prev {
+			if now.Sub(s.ts) > staleAfter {
+				delete(prev, name)
+			}
+		}
+		return ret, nil
+	}
+}
+
+// diskioDerived returns the values over the interval between the two samples,
+// as iostat -x does. The await is the average milliseconds of an I/O
+// including the time in the queue, and the util is the percent of the time
+// the device was busy.
+func diskioDerived(last, cur diskioSample, tags []report.Tag) []*report.Record {
+	dt := cur.ts.Sub(last.ts).Seconds()
+	if dt <= 0 {
+		return nil
+	}
+	// the counters have been reset, e.g. the device was re-attached
+	if cur.stat.ReadCount < last.stat.ReadCount || cur.stat.WriteCount < last.stat.WriteCount ||
+		cur.stat.ReadBytes < last.stat.ReadBytes || cur.stat.WriteBytes < last.stat.WriteBytes ||
+		cur.stat.ReadTime < last.stat.ReadTime || cur.stat.WriteTime < last.stat.WriteTime ||
+		cur.stat.IoTime < last.stat.IoTime {
+		return nil
+	}
+	reads := float64(cur.stat.ReadCount - last.stat.ReadCount)
+	writes := float64(cur.stat.WriteCount - last.stat.WriteCount)
+	readTime := float64(cur.stat.ReadTime - last.stat.ReadTime)
+	writeTime := float64(cur.stat.WriteTime - last.stat.WriteTime)
+	await := func(time, count float64) float64 {
+		if count == 0 {
+			return 0
+		}
+		return time / count
+	}
+
+	ret := []*report.Record{}
+	add := func(name string, value float64, precision int) {
+		ret = append(ret, &report.Record{Name: "diskio." + name, Value: value, Precision: precision, Tags: tags})
+	}
+	add("read_iops", reads/dt, 1)
+	add("write_iops", writes/dt, 1)
+	add("read_bytes_per_sec", float64(cur.stat.ReadBytes-last.stat.ReadBytes)/dt, 0)
+	add("write_bytes_per_sec", float64(cur.stat.WriteBytes-last.stat.WriteBytes)/dt, 0)
+	add("read_await_ms", await(readTime, reads), 2)
+	add("write_await_ms", await(writeTime, writes), 2)
+	add("await_ms", await(readTime+writeTime, reads+writes), 2)
+	add("util_percent", math.Min(100, float64(cur.stat.IoTime-last.stat.IoTime)/(dt*1000)*100), 1)
+	return ret
+}
@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskioDerived(t *testing.T) {
+	ts := time.Now()
+	last := diskioSample{ts: ts, stat: disk.IOCountersStat{
+		Name: "sda", ReadCount: 100, WriteCount: 200, ReadBytes: 4096, WriteBytes: 8192,
+		ReadTime: 50, WriteTime: 100, IoTime: 1000,
+	}}
+	cur := diskioSample{ts: ts.Add(10 * time.Second), stat: disk.IOCountersStat{
+		Name: "sda", ReadCount: 200, WriteCount: 400, ReadBytes: 4096 + 1024000, WriteBytes: 8192 + 2048000,
+		ReadTime: 250, WriteTime: 900, IoTime: 6000,
+	}}
+	values := map[string]float64{}
+	for _, r := range diskioDerived(last, cur, nil) {
+		values[r.Name] = r.Value
+	}
+	require.Equal(t, 10.0, values["diskio.read_iops"])
+	require.Equal(t, 20.0, values["diskio.write_iops"])
+	require.Equal(t, 102400.0, values["diskio.read_bytes_per_sec"])
+	require.Equal(t, 2.0, values["diskio.read_await_ms"])
+	require.Equal(t, 4.0, values["diskio.write_await_ms"])
+	require.InDelta(t, 1000.0/300, values["diskio.await_ms"], 0.001)
+	require.Equal(t, 50.0, values["diskio.util_percent"])
+
+	// the counters have been reset
+	reset := diskioSample{ts: cur.ts.Add(10 * time.Second), stat: disk.IOCountersStat{Name: "sda", ReadCount: 1}}
+	require.Nil(t, diskioDerived(cur, reset, nil))
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+)
+
+// procDir is the mount point of the proc file system.
+var procDir = "/proc"
+
+// kernelCounters are the cumulative counters of /proc/stat and /proc/vmstat,
+// that are reported with their per-second rates as <name>_rate.
+var kernelCounters = []struct {
+	name   string
+	source string // "stat" or "vmstat"
+	key    string
+}{
+	{"context_switches", "stat", "ctxt"},
+	{"interrupts", "stat", "intr"},
+	{"forks", "stat", "processes"},
+	{"minor_faults", "vmstat", "minor_faults"},
+	{"major_faults", "vmstat", "pgmajfault"},
+	{"pgpgin", "vmstat", "pgpgin"},
+	{"pgpgout", "vmstat", "pgpgout"},
+	{"pswpin", "vmstat", "pswpin"},
+	{"pswpout", "vmstat", "pswpout"},
+}
+
+// KernelInput reports the activity of the Linux kernel
+// from /proc/stat, /proc/vmstat and /proc/sys.
+//
+//	kernel.context_switches, interrupts, forks                   /proc/stat
+//	kernel.procs_running, procs_blocked, boot_time               /proc/stat
+//	kernel.minor_faults, major_faults, pgpgin, pgpgout,
+//	       pswpin, pswpout, oom_kill                             /proc/vmstat
+//	kernel.entropy_avail                                         /proc/sys/kernel/random
+//
+// The counters except oom_kill are also reported as <name>_rate per second.
+func KernelInput(_ []string) func(context.Context) ([]*report.Record, error) {
+	var lock sync.Mutex
+	prev := map[string]rateSample{}
+
+	return func(ctx context.Context) ([]*report.Record, error) {
+		stat, err := readProcStat(filepath.Join(procDir, "stat"))
+		if err != nil {
+			return nil, fmt.Errorf("inlet kernel, %s", err)
+		}
+		vmstat, err := readFlatKeyed(filepath.Join(procDir, "vmstat"))
+		if err != nil {
+			return nil, fmt.Errorf("inlet kernel, %s", err)
+		}
+		// the minor faults are not counted separately, pgfault has both
+		if v, ok := vmstat["pgfault"]; ok {
+			vmstat["minor_faults"] = v - vmstat["pgmajfault"]
+		}
+		sources := map[string]map[string]float64{"stat": stat, "vmstat": vmstat}
+
+		ret := []*report.Record{}
+		add := func(name string, value float64, precision int) {
+			ret = append(ret, &report.Record{Name: "kernel." + name, Value: value, Precision: precision})
+		}
+		add("procs_running", stat["procs_running"], 0)
+		add("procs_blocked", stat["procs_blocked"], 0)
+		add("boot_time", stat["btime"], 0)
+
+		lock.Lock()
+		now := time.Now()
+		for _, c := range kernelCounters {
+			v, ok := sources[c.source][c.key]
+			if !ok {
+				continue
+			}
+			add(c.name, v, 0)
+			cur := rateSample{ts: now, value: v}
+			if last, ok := prev[c.name]; ok {
+				if rate, ok := counterRate(last, cur); ok {
+					add(c.name+"_rate", rate, 1)
+				}
+			}
+			prev[c.name] = cur
+		}
+		lock.Unlock()
+
+		// since Linux 4.13
+		if v, ok := vmstat["oom_kill"]; ok {
+			add("oom_kill", v, 0)
+		}
+		if v, _, err := readValue(filepath.Join(procDir, "sys", "kernel", "random", "entropy_avail")); err == nil {
+			add("entropy_avail", v, 0)
+		}
+		return ret, nil
+	}
+}
+
+// readProcStat reads the first values of the lines of /proc/stat except the cpus,
+// e.g. "intr 1234 0 5 ..." is 1234 of the total.
+func readProcStat(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ret := map[string]float64{}
+	sc := bufio.NewScanner(f)
+	// the line of intr can be very long
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if v, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			ret[fields[0]] = v
+		}
+	}
+	return ret, sc.Err()
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKernelInput(t *testing.T) {
+	defer func(orig string) { procDir = orig }(procDir)
+	procDir = t.TempDir()
+	writeFiles(t, procDir, map[string]string{
+		"stat": "cpu  100 0 50 800 10 0 1 0 0 0\ncpu0 100 0 50 800 10 0 1 0 0 0\n" +
+			"intr 5000 0 10 20\nctxt 9000\nbtime 1700000000\nprocesses 300\nprocs_running 2\nprocs_blocked 1\n",
+		"vmstat": "pgpgin 10\npgpgout 20\npswpin 0\npswpout 0\npgfault 1000\npgmajfault 10\noom_kill 1\n",
+	})
+	writeFiles(t, filepath.Join(procDir, "sys", "kernel", "random"), map[string]string{"entropy_avail": "256\n"})
+
+	input := KernelInput(nil)
+	ret, err := input(context.Background())
+	require.NoError(t, err)
+	values := map[string]float64{}
+	for _, r := range ret {
+		values[r.Name] = r.Value
+	}
+	require.Equal(t, 9000.0, values["kernel.context_switches"])
+	require.Equal(t, 5000.0, values["kernel.interrupts"])
+	require.Equal(t, 300.0, values["kernel.forks"])
+	require.Equal(t, 1.0, values["kernel.procs_blocked"])
+	require.Equal(t, 990.0, values["kernel.minor_faults"])
+	require.Equal(t, 10.0, values["kernel.major_faults"])
+	require.Equal(t, 1.0, values["kernel.oom_kill"])
+	require.Equal(t, 256.0, values["kernel.entropy_avail"])
+	require.NotContains(t, values, "kernel.forks_rate")
+
+	writeFiles(t, procDir, map[string]string{
+		"stat": "ctxt 9500\nintr 5100\nprocesses 310\nprocs_running 1\nprocs_blocked 0\n",
+	})
+	ret, err = input(context.Background())
+	require.NoError(t, err)
+	for _, r := range ret {
+		values[r.Name] = r.Value
+	}
+	require.Greater(t, values["kernel.forks_rate"], 0.0)
+	require.Greater(t, values["kernel.context_switches_rate"], 0.0)
+}
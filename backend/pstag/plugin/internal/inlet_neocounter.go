@@ -1,22 +1,23 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"neo-cat/backend/pstag/report"
 	"strings"
 )
 
-func NeoTableRowsCounterInput(args []string) func() ([]*report.Record, error) {
+func NeoTableRowsCounterInput(args []string) func(context.Context) ([]*report.Record, error) {
 	InitNeoHttpClient(args[0])
 	tables := []string{}
 	if len(args) >= 2 {
 		tables = args[1:]
 	}
 
-	return func() ([]*report.Record, error) {
+	return func(ctx context.Context) ([]*report.Record, error) {
 		ret := []*report.Record{}
 		for _, table := range tables {
-			rsp, err := DefaultNeoHttpClient().GetCountTable(table)
+			rsp, err := DefaultNeoHttpClient().GetCountTable(ctx, table)
 			if err != nil {
 				return nil, fmt.Errorf("inlet_neo_table_rows_counter %s", err)
 			}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"neo-cat/backend/pstag/report"
 )
@@ -54,11 +55,11 @@ type NeoStatz struct {
 	} `json:"sess"`
 }
 
-func NeoStatzInput(args []string) func() ([]*report.Record, error) {
+func NeoStatzInput(args []string) func(context.Context) ([]*report.Record, error) {
 	InitNeoHttpClient(args[0])
 
-	return func() ([]*report.Record, error) {
-		o, err := neoHttpClient.GetStatz()
+	return func(ctx context.Context) ([]*report.Record, error) {
+		o, err := neoHttpClient.GetStatz(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("inlet_neo_statz %s", err)
 		}
@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// sysClassNet is the directory of the network interfaces of Linux.
+var sysClassNet = "/sys/class/net"
+
+// netLink is the state of the link of an interface in /sys/class/net/<if>.
+type netLink struct {
+	up             bool
+	speed          float64 // Mbps, zero if unknown
+	mtu            float64
+	carrierChanges float64
+	hasCarrier     bool // carrier_changes is available
+}
+
+func readNetLink(name string) (netLink, bool) {
+	dir := filepath.Join(sysClassNet, name)
+	b, err := os.ReadFile(filepath.Join(dir, "operstate"))
+	if err != nil {
+		return netLink{}, false
+	}
+	ret := netLink{}
+	switch strings.TrimSpace(string(b)) {
+	case "up":
+		ret.up = true
+	case "unknown":
+		// e.g. lo and tun, that have no notion of the operstate
+		if v, _, err := readValue(filepath.Join(dir, "carrier")); err == nil {
+			ret.up = v == 1
+		}
+	}
+	// the speed is -1 or not readable when the link is down or virtual
+	if v, _, err := readValue(filepath.Join(dir, "speed")); err == nil && v > 0 {
+		ret.speed = v
+	}
+	if v, _, err := readValue(filepath.Join(dir, "mtu")); err == nil {
+		ret.mtu = v
+	}
+	if v, _, err := readValue(filepath.Join(dir, "carrier_changes")); err == nil {
+		ret.carrierChanges, ret.hasCarrier = v, true
+	}
+	return ret, true
+}
+
+type netSample struct {
+	ts        time.Time
+	bytesSent float64
+	bytesRecv float64
+	link      netLink
+}
+
+// NetInput reports the network I/O of the interfaces,
+// args[0] is glob patterns of the interfaces, comma(,) separated.
+//
+// On Linux the link of the interface is read from /sys/class/net/<if>,
+// net.link_up is 1 or 0, net.carrier_flaps is the carrier changes since the previous
+// collection, and net.utilization_in_percent and net.utilization_out_percent
+// are the bandwidth used over the speed of the link.
+func NetInput(args []string) func(context.Context) ([]*report.Record, error) {
+	nicPatterns := splitPatterns(args)
+
+	var lock sync.Mutex
+	prev := map[string]netSample{}
+
+	return func(ctx context.Context) ([]*report.Record, error) {
+		stat, err := net.IOCountersWithContext(ctx, true)
+		if err != nil {
+			return nil, fmt.Errorf("inlet net, %s", err)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+
+		now := time.Now()
+		ret := []*report.Record{}
+		for _, v := range stat {
+			if !matchPatterns(nicPatterns, v.Name) {
+				continue
+			}
+			tags := []report.Tag{{Key: "interface", Value: v.Name}}
+			add := func(name string, value float64, precision int) {
+				ret = append(ret, &report.Record{Name: "net." + name, Value: value, Precision: precision, Tags: tags})
+			}
+			add("bytes_sent", float64(v.BytesSent), 0)
+			add("bytes_recv", float64(v.BytesRecv), 0)
+			add("packets_sent", float64(v.PacketsSent), 0)
+			add("packets_recv", float64(v.PacketsRecv), 0)
+			add("drop_in", float64(v.Dropin), 0)
+			add("drop_out", float64(v.Dropout), 0)
+			add("err_in", float64(v.Errin), 0)
+			add("err_out", float64(v.Errout), 0)
+			add("fifo_in", float64(v.Fifoin), 0)
+			add("fifo_out", float64(v.Fifoout), 0)
+
+			link, ok := readNetLink(v.Name)
+			if !ok {
+				continue
+			}
+			add("link_up", boolValue(link.up), 0)
+			add("mtu", link.mtu, 0)
+			if link.speed > 0 {
+				add("speed_mbps", link.speed, 0)
+			}
+			if link.hasCarrier {
+				add("carrier_changes", link.carrierChanges, 0)
+			}
+
+			cur := netSample{ts: now, bytesSent: float64(v.BytesSent), bytesRecv: float64(v.BytesRecv), link: link}
+			if last, ok := prev[v.Name]; ok {
+				ret = append(ret, netDerived(last, cur, tags)...)
+			}
+			prev[v.Name] = cur
+		}
+		for name, s := range prev {
+			if now.Sub(s.ts) > staleAfter {
+				delete(prev, name)
+			}
+		}
+		return ret, nil
+	}
+}
+
+// netDerived returns the carrier flaps and the utilization between the two samples.
+func netDerived(last, cur netSample, tags []report.Tag) []*report.Record {
+	ret := []*report.Record{}
+	add := func(name string, value float64, precision int) {
+		ret = append(ret, &report.Record{Name: "net." + name, Value: value, Precision: precision, Tags: tags})
+	}
+	if cur.link.hasCarrier && last.link.hasCarrier && cur.link.carrierChanges >= last.link.carrierChanges {
+		add("carrier_flaps", cur.link.carrierChanges-last.link.carrierChanges, 0)
+	}
+	if cur.link.speed <= 0 {
+		return ret
+	}
+	// the counters have been reset, e.g. the interface was re-created
+	if cur.bytesRecv < last.bytesRecv || cur.bytesSent < last.bytesSent {
+		return ret
+	}
+	// the counters may run ahead of the negotiated speed, e.g. of the virtual interfaces
+	bitsPerSec := cur.link.speed * 1e6
+	if rate, ok := counterRate(rateSample{ts: last.ts, value: last.bytesRecv}, rateSample{ts: cur.ts, value: cur.bytesRecv}); ok {
+		add("utilization_in_percent", math.Min(100, rate*8/bitsPerSec*100), 2)
+	}
+	if rate, ok := counterRate(rateSample{ts: last.ts, value: last.bytesSent}, rateSample{ts: cur.ts, value: cur.bytesSent}); ok {
+		add("utilization_out_percent", math.Min(100, rate*8/bitsPerSec*100), 2)
+	}
+	return ret
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadNetLink(t *testing.T) {
+	defer func(orig string) { sysClassNet = orig }(sysClassNet)
+	sysClassNet = t.TempDir()
+	writeFiles(t, filepath.Join(sysClassNet, "eth0"), map[string]string{
+		"operstate": "up\n", "speed": "1000\n", "mtu": "1500\n", "carrier_changes": "3\n",
+	})
+	writeFiles(t, filepath.Join(sysClassNet, "lo"), map[string]string{
+		"operstate": "unknown\n", "carrier": "1\n", "mtu": "65536\n",
+	})
+	writeFiles(t, filepath.Join(sysClassNet, "eth1"), map[string]string{
+		"operstate": "down\n", "speed": "-1\n", "mtu": "1500\n", "carrier_changes": "0\n",
+	})
+
+	link, ok := readNetLink("eth0")
+	require.True(t, ok)
+	require.Equal(t, netLink{up: true, speed: 1000, mtu: 1500, carrierChanges: 3, hasCarrier: true}, link)
+
+	link, ok = readNetLink("lo")
+	require.True(t, ok)
+	require.True(t, link.up)
+	require.False(t, link.hasCarrier)
+
+	link, ok = readNetLink("eth1")
+	require.True(t, ok)
+	require.False(t, link.up)
+	require.Equal(t, 0.0, link.speed)
+
+	_, ok = readNetLink("wlan0")
+	require.False(t, ok)
+}
+
+func TestNetDerived(t *testing.T) {
+	now := time.Now()
+	link := netLink{up: true, speed: 100, carrierChanges: 3, hasCarrier: true}
+	last := netSample{ts: now, link: link}
+	link.carrierChanges = 5
+	// 6.25MB/s is 50% of 100Mbps
+	cur := netSample{ts: now.Add(2 * time.Second), bytesRecv: 12_500_000, bytesSent: 1_250_000, link: link}
+
+	values := map[string]float64{}
+	for _, r := range netDerived(last, cur, []report.Tag{{Key: "interface", Value: "eth0"}}) {
+		require.Equal(t, "eth0", r.Tag("interface"))
+		values[r.Name] = r.Value
+	}
+	require.Equal(t, 2.0, values["net.carrier_flaps"])
+	require.InDelta(t, 50.0, values["net.utilization_in_percent"], 0.001)
+	require.InDelta(t, 5.0, values["net.utilization_out_percent"], 0.001)
+
+	// the utilization is capped at 100%
+	cur.bytesRecv = 50_000_000
+	values = map[string]float64{}
+	for _, r := range netDerived(last, cur, nil) {
+		values[r.Name] = r.Value
+	}
+	require.Equal(t, 100.0, values["net.utilization_in_percent"])
+
+	// no utilization when a counter has been reset
+	reset := netSample{ts: cur.ts.Add(2 * time.Second), bytesRecv: 60_000_000, bytesSent: 1_000, link: link}
+	values = map[string]float64{}
+	for _, r := range netDerived(cur, reset, nil) {
+		values[r.Name] = r.Value
+	}
+	require.Contains(t, values, "net.carrier_flaps")
+	require.NotContains(t, values, "net.utilization_in_percent")
+	require.NotContains(t, values, "net.utilization_out_percent")
+
+	// no utilization without the speed of the link
+	cur.link.speed = 0
+	values = map[string]float64{}
+	for _, r := range netDerived(last, cur, nil) {
+		values[r.Name] = r.Value
+	}
+	require.Contains(t, values, "net.carrier_flaps")
+	require.NotContains(t, values, "net.utilization_in_percent")
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+)
+
+// procPressureDir is the directory of the system-wide pressure stall information.
+var procPressureDir = "/proc/pressure"
+
+var pressureResources = []string{"cpu", "memory", "io"}
+
+// PressureInput reports the pressure stall information (PSI) of Linux 4.20+,
+// of the system and of the cgroups (v2).
+//
+//	args[0]         the resources, comma(,) separated, default cpu,memory,io
+//	cgroups=<glob>  glob patterns of the cgroups relative to the root, comma(,) separated
+//	root=<path>     the mount point of the cgroup2 file system, default /sys/fs/cgroup
+//
+// The records are pressure.<some|full>_avg10, _avg60, _avg300 in percent,
+// <some|full>_total of the stall time in microseconds and <some|full>_total_rate
+// of the stall time per second, tagged with resource=<resource> and cgroup=<path>.
+func PressureInput(args []string) func(context.Context) ([]*report.Record, error) {
+	args, opts := parseOptions(args)
+	resources := splitPatterns(args)
+	if len(resources) == 0 {
+		resources = pressureResources
+	}
+	cgroups := splitPatterns([]string{opts["cgroups"]})
+	root := defaultCgroupRoot
+	if v := strings.TrimSpace(opts["root"]); v != "" {
+		root = v
+	}
+
+	var lock sync.Mutex
+	prev := map[string]rateSample{}
+
+	return func(ctx context.Context) ([]*report.Record, error) {
+		type source struct {
+			path string
+			tags []report.Tag
+		}
+		sources := []source{}
+		for _, res := range resources {
+			sources = append(sources, source{
+				path: filepath.Join(procPressureDir, res),
+				tags: []report.Tag{{Key: "resource", Value: res}},
+			})
+		}
+		if len(cgroups) > 0 {
+			dirs, err := cgroupDirs(root, cgroups)
+			if err != nil {
+				return nil, fmt.Errorf("inlet pressure, %s", err)
+			}
+			for _, dir := range dirs {
+				for _, res := range resources {
+					sources = append(sources, source{
+						path: filepath.Join(dir, res+".pressure"),
+						tags: []report.Tag{{Key: "resource", Value: res}, {Key: "cgroup", Value: cgroupName(root, dir)}},
+					})
+				}
+			}
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		now := time.Now()
+		ret := []*report.Record{}
+		seen := map[string]bool{}
+		for _, src := range sources {
+			psi, err := readPressure(src.path)
+			if err != nil {
+				if os.IsNotExist(err) && len(src.tags) > 1 {
+					// the controller is not enabled in the cgroup
+					continue
+				}
+				return nil, fmt.Errorf("inlet pressure, %s", err)
+			}
+			for _, kind := range []string{"some", "full"} {
+				values, ok := psi[kind]
+				if !ok {
+					continue
+				}
+				add := func(field string, value float64, precision int) {
+					ret = append(ret, &report.Record{Name: "pressure." + kind + "_" + field, Value: value, Precision: precision, Tags: src.tags})
+				}
+				add("avg10", values["avg10"], 2)
+				add("avg60", values["avg60"], 2)
+				add("avg300", values["avg300"], 2)
+				add("total", values["total"], 0)
+
+				key := src.path + ":" + kind
+				seen[key] = true
+				cur := rateSample{ts: now, value: values["total"]}
+				if last, ok := prev[key]; ok {
+					if rate, ok := counterRate(last, cur); ok {
+						add("total_rate", rate, 0)
+					}
+				}
+				prev[key] = cur
+			}
+		}
+		for key := range prev {
+			if !seen[key] {
+				delete(prev, key)
+			}
+		}
+		return ret, nil
+	}
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPressureInput(t *testing.T) {
+	dir := t.TempDir()
+	defer func(orig string) { procPressureDir = orig }(procPressureDir)
+	procPressureDir = filepath.Join(dir, "pressure")
+	writeFiles(t, procPressureDir, map[string]string{
+		"cpu":    "some avg10=5.91 avg60=3.16 avg300=2.16 total=59898942\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		"memory": "some avg10=0.00 avg60=0.00 avg300=0.00 total=100\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=50\n",
+	})
+	root := filepath.Join(dir, "cgroup")
+	writeFiles(t, root, map[string]string{"cgroup.controllers": "cpu memory\n"})
+	writeFiles(t, filepath.Join(root, "neo.service"), map[string]string{
+		"cpu.pressure": "some avg10=1.00 avg60=0.50 avg300=0.10 total=1000\nfull avg10=0.50 avg60=0.20 avg300=0.05 total=500\n",
+	})
+
+	input := PressureInput([]string{"cpu,memory", "cgroups=*.service", "root=" + root})
+	ret, err := input(context.Background())
+	require.NoError(t, err)
+	find := func(ret []*report.Record, name, resource, cgroup string) *report.Record {
+		for _, r := range ret {
+			if r.Name == name && r.Tag("resource") == resource && r.Tag("cgroup") == cgroup {
+				return r
+			}
+		}
+		return nil
+	}
+	require.Equal(t, 5.91, find(ret, "pressure.some_avg10", "cpu", "").Value)
+	require.Equal(t, 50.0, find(ret, "pressure.full_total", "memory", "").Value)
+	require.Equal(t, 0.5, find(ret, "pressure.full_avg10", "cpu", "neo.service").Value)
+	require.Nil(t, find(ret, "pressure.some_total_rate", "cpu", ""))
+
+	writeFiles(t, procPressureDir, map[string]string{
+		"cpu": "some avg10=5.91 avg60=3.16 avg300=2.16 total=59998942\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+	})
+	ret, err = input(context.Background())
+	require.NoError(t, err)
+	require.Greater(t, find(ret, "pressure.some_total_rate", "cpu", "").Value, 0.0)
+
+	// io is not available
+	_, err = PressureInput([]string{"io"})(context.Background())
+	require.Error(t, err)
+}
@@ -0,0 +1,317 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// procCache keeps the processes between the collections,
+// as the CPU percent of a process is derived from its previous CPU times.
+type procCache struct {
+	procs map[int32]*procEntry
+}
+
+type procEntry struct {
+	proc    *process.Process
+	name    string
+	created int64 // milliseconds since the epoch
+	sampled bool  // the CPU times has been taken
+	seen    bool
+}
+
+func newProcCache() *procCache {
+	return &procCache{procs: map[int32]*procEntry{}}
+}
+
+// get returns the process of the pid, the cached one is returned
+// unless the pid has been reused by another process.
+func (c *procCache) get(ctx context.Context, pid int32) (*procEntry, error) {
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	created, err := p.CreateTimeWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ent, ok := c.procs[pid]; ok && ent.created == created {
+		ent.seen = true
+		return ent, nil
+	}
+	name, err := p.NameWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ent := &procEntry{proc: p, name: name, created: created, seen: true}
+	c.procs[pid] = ent
+	return ent, nil
+}
+
+// sweep forgets the processes that were not seen since the previous sweep.
+func (c *procCache) sweep() {
+	for pid, ent := range c.procs {
+		if !ent.seen {
+			delete(c.procs, pid)
+			continue
+		}
+		ent.seen = false
+	}
+}
+
+// cpuPercent returns the CPU percent of the process since the previous collection,
+// 100 is a core. It returns false for the first collection of the process.
+func (ent *procEntry) cpuPercent(ctx context.Context) (float64, bool) {
+	v, err := ent.proc.PercentWithContext(ctx, 0)
+	if err != nil {
+		return 0, false
+	}
+	ok := ent.sampled
+	ent.sampled = true
+	return v, ok
+}
+
+// procSelector selects the processes that match all of the given selectors.
+type procSelector struct {
+	names   []string
+	cmdline *regexp.Regexp
+	pidfile string
+	user    string
+}
+
+func (sel *procSelector) empty() bool {
+	return len(sel.names) == 0 && sel.cmdline == nil && sel.pidfile == "" && sel.user == ""
+}
+
+func (sel *procSelector) pids(ctx context.Context) ([]int32, error) {
+	if sel.pidfile == "" {
+		return process.PidsWithContext(ctx)
+	}
+	b, err := os.ReadFile(sel.pidfile)
+	if err != nil {
+		// the process is not running
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("pidfile %s, %s", sel.pidfile, err)
+	}
+	return []int32{int32(pid)}, nil
+}
+
+func (sel *procSelector) match(ctx context.Context, ent *procEntry) bool {
+	if len(sel.names) > 0 && !matchPatterns(sel.names, ent.name) {
+		return false
+	}
+	if sel.cmdline != nil {
+		cmdline, err := ent.proc.CmdlineWithContext(ctx)
+		if err != nil || !sel.cmdline.MatchString(cmdline) {
+			return false
+		}
+	}
+	if sel.user != "" {
+		user, err := ent.proc.UsernameWithContext(ctx)
+		if err != nil || user != sel.user {
+			return false
+		}
+	}
+	return true
+}
+
+// ProcstatInput reports the processes that match all of the selectors,
+// the selectors are given as "key=value" args.
+//
+//	name=<glob>       the name of the process, comma(,) separated
+//	cmdline=<regexp>  the command line of the process
+//	pidfile=<path>    the file that has the pid of the process
+//	user=<user>       the user of the process
+//	pid_tag=true      report each process tagged with the pid
+//	label=<label>     the selector tag of procstat.count
+//
+// The records are tagged with process=<name>, the processes of the same name,
+// e.g. the workers of a pool, are summed up and procstat.uptime is of the youngest one,
+// so that a restart of any of them is shown. procstat.cpu_percent of them is left out
+// until every one has the previous sample, not to be short of the new processes.
+// procstat.count of the matched processes is tagged with selector=<label>,
+// that is the name selector, the pidfile, the user or "cmdline" if not given.
+// A restart of the process is shown as the reset of procstat.uptime.
+func ProcstatInput(args []string) func(context.Context) ([]*report.Record, error) {
+	_, opts := parseOptions(args)
+	sel := &procSelector{
+		names:   splitPatterns([]string{opts["name"]}),
+		pidfile: strings.TrimSpace(opts["pidfile"]),
+		user:    strings.TrimSpace(opts["user"]),
+	}
+	var selErr error
+	if expr := opts["cmdline"]; expr != "" {
+		if sel.cmdline, selErr = regexp.Compile(expr); selErr != nil {
+			selErr = fmt.Errorf("inlet procstat, invalid cmdline %q", expr)
+		}
+	}
+	if selErr == nil && sel.empty() {
+		selErr = fmt.Errorf("inlet procstat, no selector of name, cmdline, pidfile and user")
+	}
+	pidTag, _ := strconv.ParseBool(opts["pid_tag"])
+	selector := procSelectorLabel(sel, strings.TrimSpace(opts["label"]))
+
+	var lock sync.Mutex
+	cache := newProcCache()
+
+	return func(ctx context.Context) ([]*report.Record, error) {
+		if selErr != nil {
+			return nil, selErr
+		}
+		lock.Lock()
+		defer lock.Unlock()
+
+		pids, err := sel.pids(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("inlet procstat, %s", err)
+		}
+		ret := []*report.Record{}
+		sums := newProcstatSum()
+		count := 0
+		now := time.Now()
+		for _, pid := range pids {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("inlet procstat, %s", ctx.Err())
+			}
+			// the process may have exited
+			ent, err := cache.get(ctx, pid)
+			if err != nil || !sel.match(ctx, ent) {
+				continue
+			}
+			count++
+			tags := []report.Tag{{Key: "process", Value: ent.name}}
+			if !pidTag {
+				sums.add(ent.name, procstatRecords(ctx, ent, now, tags))
+				continue
+			}
+			tags = append(tags, report.Tag{Key: "pid", Value: strconv.Itoa(int(pid))})
+			ret = append(ret, procstatRecords(ctx, ent, now, tags)...)
+		}
+		cache.sweep()
+		ret = append(ret, sums.records()...)
+		ret = append(ret, &report.Record{Name: "procstat.count", Value: float64(count), Precision: 0,
+			Tags: []report.Tag{{Key: "selector", Value: selector}}})
+		return ret, nil
+	}
+}
+
+// procSelectorLabel returns the label of the selector for the tag,
+// that has no path and no "=" not to make the names of the series odd.
+func procSelectorLabel(sel *procSelector, label string) string {
+	if label == "" {
+		switch {
+		case len(sel.names) > 0:
+			label = strings.Join(sel.names, ",")
+		case sel.pidfile != "":
+			label = strings.TrimSuffix(filepath.Base(sel.pidfile), filepath.Ext(sel.pidfile))
+		case sel.user != "":
+			label = sel.user
+		default:
+			label = "cmdline"
+		}
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '=' || unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, label)
+}
+
+// procstatSum sums up the records of the processes of the same name,
+// except procstat.uptime that is of the youngest process.
+// procstat.cpu_percent is left out if any of the processes has not it yet.
+type procstatSum struct {
+	order  []string
+	groups map[string][]*report.Record
+	noCPU  map[string]bool
+}
+
+func newProcstatSum() *procstatSum {
+	return &procstatSum{groups: map[string][]*report.Record{}, noCPU: map[string]bool{}}
+}
+
+func (ps *procstatSum) add(name string, recs []*report.Record) {
+	group, ok := ps.groups[name]
+	if !ok {
+		ps.order = append(ps.order, name)
+	}
+	// the process seen for the first time has no cpu_percent
+	if !slices.ContainsFunc(recs, func(r *report.Record) bool { return r.Name == "procstat.cpu_percent" }) {
+		ps.noCPU[name] = true
+	}
+	for _, rec := range recs {
+		idx := slices.IndexFunc(group, func(r *report.Record) bool { return r.Name == rec.Name })
+		switch {
+		case idx < 0:
+			group = append(group, rec)
+		case rec.Name == "procstat.uptime":
+			group[idx].Value = min(group[idx].Value, rec.Value)
+		default:
+			group[idx].Value += rec.Value
+		}
+	}
+	ps.groups[name] = group
+}
+
+func (ps *procstatSum) records() []*report.Record {
+	ret := []*report.Record{}
+	for _, name := range ps.order {
+		for _, rec := range ps.groups[name] {
+			if ps.noCPU[name] && rec.Name == "procstat.cpu_percent" {
+				continue
+			}
+			ret = append(ret, rec)
+		}
+	}
+	return ret
+}
+
+func procstatRecords(ctx context.Context, ent *procEntry, now time.Time, tags []report.Tag) []*report.Record {
+	ret := []*report.Record{}
+	add := func(name string, value float64, precision int) {
+		ret = append(ret, &report.Record{Name: "procstat." + name, Value: value, Precision: precision, Tags: tags})
+	}
+	if v, ok := ent.cpuPercent(ctx); ok {
+		add("cpu_percent", v, 1)
+	}
+	if mi, err := ent.proc.MemoryInfoWithContext(ctx); err == nil {
+		add("memory_rss", float64(mi.RSS), 0)
+		add("memory_vms", float64(mi.VMS), 0)
+	}
+	// the fds and the io need the permission to the process
+	if v, err := ent.proc.NumFDsWithContext(ctx); err == nil {
+		add("num_fds", float64(v), 0)
+	}
+	if v, err := ent.proc.NumThreadsWithContext(ctx); err == nil {
+		add("num_threads", float64(v), 0)
+	}
+	if io, err := ent.proc.IOCountersWithContext(ctx); err == nil {
+		add("read_bytes", float64(io.ReadBytes), 0)
+		add("write_bytes", float64(io.WriteBytes), 0)
+	}
+	if cs, err := ent.proc.NumCtxSwitchesWithContext(ctx); err == nil {
+		add("voluntary_ctx_switches", float64(cs.Voluntary), 0)
+		add("involuntary_ctx_switches", float64(cs.Involuntary), 0)
+	}
+	add("uptime", now.Sub(time.UnixMilli(ent.created)).Seconds(), 0)
+	return ret
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcstatInput(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "test.pid")
+	require.NoError(t, os.WriteFile(pidfile, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644))
+
+	input := ProcstatInput([]string{"pidfile=" + pidfile, "pid_tag=true"})
+	ret, err := input(context.Background())
+	require.NoError(t, err)
+	values := map[string]*report.Record{}
+	for _, r := range ret {
+		values[r.Name] = r
+	}
+	require.Equal(t, 1.0, values["procstat.count"].Value)
+	require.Greater(t, values["procstat.memory_rss"].Value, 0.0)
+	require.Equal(t, fmt.Sprint(os.Getpid()), values["procstat.memory_rss"].Tag("pid"))
+	require.NotContains(t, values, "procstat.cpu_percent")
+
+	// the cpu percent is reported from the second collection
+	ret, err = input(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, names(newReport(time.Now(), ret...)), "procstat.cpu_percent")
+
+	// not running
+	require.NoError(t, os.Remove(pidfile))
+	ret, err = input(context.Background())
+	require.NoError(t, err)
+	require.Len(t, ret, 1)
+	require.Equal(t, 0.0, ret[0].Value)
+
+	_, err = ProcstatInput([]string{"pid_tag=true"})(context.Background())
+	require.Error(t, err)
+}
+
+func TestProcstatSum(t *testing.T) {
+	worker := func(cpu, rss, uptime float64) []*report.Record {
+		tags := []report.Tag{{Key: "process", Value: "worker"}}
+		ret := []*report.Record{}
+		// the process seen for the first time has no cpu_percent, as cpu < 0 here
+		if cpu >= 0 {
+			ret = append(ret, &report.Record{Name: "procstat.cpu_percent", Value: cpu, Tags: tags})
+		}
+		return append(ret,
+			&report.Record{Name: "procstat.memory_rss", Value: rss, Tags: tags},
+			&report.Record{Name: "procstat.uptime", Value: uptime, Tags: tags})
+	}
+	values := func(ps *procstatSum) map[string]float64 {
+		ret := map[string]float64{}
+		for _, r := range ps.records() {
+			ret[r.Name+":"+r.Tag("process")] = r.Value
+		}
+		return ret
+	}
+
+	ps := newProcstatSum()
+	ps.add("worker", worker(10, 100, 30))
+	ps.add("worker", worker(20, 200, 50))
+	ps.add("neo", []*report.Record{{Name: "procstat.memory_rss", Value: 50, Tags: []report.Tag{{Key: "process", Value: "neo"}}}})
+	require.Equal(t, map[string]float64{
+		"procstat.cpu_percent:worker": 30,
+		"procstat.memory_rss:worker":  300,
+		"procstat.uptime:worker":      30,
+		"procstat.memory_rss:neo":     50,
+	}, values(ps))
+
+	// a new worker leaves out the cpu_percent of the group and resets the uptime
+	ps = newProcstatSum()
+	ps.add("worker", worker(10, 100, 30))
+	ps.add("worker", worker(-1, 100, 1))
+	require.Equal(t, map[string]float64{
+		"procstat.memory_rss:worker": 200,
+		"procstat.uptime:worker":     1,
+	}, values(ps))
+
+	require.Equal(t, "machbase-neo", procSelectorLabel(&procSelector{names: []string{"machbase-neo"}}, ""))
+	require.Equal(t, "neo", procSelectorLabel(&procSelector{pidfile: "/var/run/neo.pid"}, ""))
+	require.Equal(t, "my_app", procSelectorLabel(&procSelector{user: "neo"}, "my app"))
+}
@@ -1,15 +1,19 @@
 package internal
 
 import (
+	"context"
 	"fmt"
-	"path/filepath"
+	"log/slog"
+	"math"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"neo-cat/backend/pstag/report"
 
 	"github.com/shirou/gopsutil/v4/cpu"
-	"github.com/shirou/gopsutil/v4/disk"
 	"github.com/shirou/gopsutil/v4/host"
 	"github.com/shirou/gopsutil/v4/load"
 	"github.com/shirou/gopsutil/v4/mem"
@@ -17,20 +21,110 @@ import (
 	"github.com/shirou/gopsutil/v4/sensors"
 )
 
-func CpuInput() ([]*report.Record, error) {
-	v, err := cpu.Percent(0, false)
-	if err != nil {
-		return nil, fmt.Errorf("inlet cpu, %s", err)
+// CpuInput reports the CPU usage, args[0] selects the metrics, comma(,) separated.
+//
+//	total   cpu.percent of all CPUs (default)
+//	percpu  cpu.percent of each CPU, tagged with cpu=cpu0, cpu1, ...
+//	times   cpu.<state>_percent, the share of user, system, idle, nice, iowait,
+//	        irq, softirq and steal over the interval, also of each CPU with percpu
+func CpuInput(args []string) func(context.Context) ([]*report.Record, error) {
+	metrics := splitPatterns(args)
+	if len(metrics) == 0 {
+		metrics = []string{"total"}
 	}
-	ret := []*report.Record{}
-	for _, p := range v {
-		ret = append(ret, &report.Record{Name: "cpu.percent", Value: p, Precision: 1})
+	total := slices.Contains(metrics, "total")
+	percpu := slices.Contains(metrics, "percpu")
+	times := slices.Contains(metrics, "times")
+
+	// the previous times by cpu, "cpu-total" for all CPUs
+	var lock sync.Mutex
+	prev := map[string]cpu.TimesStat{}
+
+	return func(ctx context.Context) ([]*report.Record, error) {
+		ret := []*report.Record{}
+		if total {
+			v, err := cpu.PercentWithContext(ctx, 0, false)
+			if err != nil {
+				return nil, fmt.Errorf("inlet cpu, %s", err)
+			}
+			for _, p := range v {
+				ret = append(ret, &report.Record{Name: "cpu.percent", Value: p, Precision: 1})
+			}
+		}
+		if percpu {
+			v, err := cpu.PercentWithContext(ctx, 0, true)
+			if err != nil {
+				return nil, fmt.Errorf("inlet cpu, %s", err)
+			}
+			for i, p := range v {
+				ret = append(ret, &report.Record{Name: "cpu.percent", Value: p, Precision: 1,
+					Tags: []report.Tag{{Key: "cpu", Value: fmt.Sprintf("cpu%d", i)}}})
+			}
+		}
+		if times {
+			stat, err := cpu.TimesWithContext(ctx, false)
+			if err != nil {
+				return nil, fmt.Errorf("inlet cpu, %s", err)
+			}
+			if percpu {
+				perStat, err := cpu.TimesWithContext(ctx, true)
+				if err != nil {
+					return nil, fmt.Errorf("inlet cpu, %s", err)
+				}
+				stat = append(stat, perStat...)
+			}
+			lock.Lock()
+			for _, cur := range stat {
+				last, ok := prev[cur.CPU]
+				prev[cur.CPU] = cur
+				if !ok {
+					continue
+				}
+				tags := []report.Tag{}
+				if percpu && cur.CPU != "cpu-total" {
+					tags = append(tags, report.Tag{Key: "cpu", Value: cur.CPU})
+				}
+				ret = append(ret, cpuTimesPercent(last, cur, tags)...)
+			}
+			lock.Unlock()
+		}
+		return ret, nil
 	}
-	return ret, nil
 }
 
-func LoadInput() ([]*report.Record, error) {
-	stat, err := load.Avg()
+// cpuTimesPercent returns the share of each state between the two times.
+// The guest times are not counted, as they are included in user and nice.
+func cpuTimesPercent(last, cur cpu.TimesStat, tags []report.Tag) []*report.Record {
+	states := []struct {
+		name      string
+		last, cur float64
+	}{
+		{"user", last.User, cur.User},
+		{"system", last.System, cur.System},
+		{"idle", last.Idle, cur.Idle},
+		{"nice", last.Nice, cur.Nice},
+		{"iowait", last.Iowait, cur.Iowait},
+		{"irq", last.Irq, cur.Irq},
+		{"softirq", last.Softirq, cur.Softirq},
+		{"steal", last.Steal, cur.Steal},
+	}
+	total := 0.0
+	for _, st := range states {
+		total += st.cur - st.last
+	}
+	if total <= 0 {
+		return nil
+	}
+	ret := make([]*report.Record, 0, len(states))
+	for _, st := range states {
+		pct := math.Max(0, (st.cur-st.last)/total*100)
+		ret = append(ret, &report.Record{Name: "cpu." + st.name + "_percent", Value: pct, Precision: 1, Tags: tags})
+	}
+	return ret
+}
+
+func LoadInput(ctx context.Context) ([]*report.Record, error) {
+	stat, err := load.AvgWithContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("inlet load, %s", err)
 	}
@@ -42,204 +136,98 @@ func LoadInput() ([]*report.Record, error) {
 	return ret, nil
 }
 
-func MemInput() ([]*report.Record, error) {
-	stat, err := mem.VirtualMemory()
-	if err != nil {
-		return nil, err
-	}
-	ret := []*report.Record{
-		{Name: "mem.total", Value: float64(stat.Total), Precision: 0},
-		{Name: "mem.free", Value: float64(stat.Free), Precision: 0},
-		{Name: "mem.used", Value: float64(stat.Used), Precision: 0},
-		{Name: "mem.used_percent", Value: stat.UsedPercent, Precision: 1},
-	}
-	return ret, nil
-}
+// the sources of MemInput, they are replaced in the tests
+var (
+	virtualMemory = mem.VirtualMemoryWithContext
+	swapMemory    = mem.SwapMemoryWithContext
+)
 
-func DiskInput(args []string) func() ([]*report.Record, error) {
-	mountpoints := strings.Split(args[0], ",")
-	return func() ([]*report.Record, error) {
-		stat, err := disk.Partitions(false)
+// MemInput reports the memory and the swap usage, in bytes.
+// The swap.in_rate and swap.out_rate are the bytes per second
+// swapped in and out since the previous collection.
+// If the swap is not available, e.g. in some containers, only the memory is reported.
+func MemInput(_ []string) func(context.Context) ([]*report.Record, error) {
+	var lock sync.Mutex
+	var prevIn, prevOut rateSample
+	var swapFailed bool
+
+	return func(ctx context.Context) ([]*report.Record, error) {
+		stat, err := virtualMemory(ctx)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("inlet mem, %s", err)
 		}
-		ret := []*report.Record{}
-		for _, v := range stat {
-			matched := false
-			for _, point := range mountpoints {
-				if point == "all" || point == v.Mountpoint {
-					matched = true
-					break
-				}
-			}
-			if !matched {
-				continue
-			}
-			usage, err := disk.Usage(v.Mountpoint)
-			if err != nil {
-				return nil, fmt.Errorf("inlet disk, %s", err)
-			}
-			ret = append(ret,
-				&report.Record{
-					Name:      fmt.Sprintf("disk.%s.total", v.Mountpoint),
-					Value:     float64(usage.Total),
-					Precision: 0,
-				},
-				&report.Record{
-					Name:      fmt.Sprintf("disk.%s.free", v.Mountpoint),
-					Value:     float64(usage.Free),
-					Precision: 0,
-				},
-				&report.Record{
-					Name:      fmt.Sprintf("disk.%s.used", v.Mountpoint),
-					Value:     float64(usage.Used),
-					Precision: 0,
-				},
-				&report.Record{
-					Name:      fmt.Sprintf("disk.%s.used_percent", v.Mountpoint),
-					Value:     usage.UsedPercent,
-					Precision: 1,
-				},
-			)
-			if runtime.GOOS != "windows" {
-				ret = append(ret,
-					&report.Record{
-						Name:      fmt.Sprintf("disk.%s.inodes_total", v.Mountpoint),
-						Value:     float64(usage.InodesTotal),
-						Precision: 0,
-					},
-					&report.Record{
-						Name:      fmt.Sprintf("disk.%s.inodes_free", v.Mountpoint),
-						Value:     float64(usage.InodesFree),
-						Precision: 0,
-					},
-					&report.Record{
-						Name:      fmt.Sprintf("disk.%s.inodes_used", v.Mountpoint),
-						Value:     float64(usage.InodesUsed),
-						Precision: 0,
-					},
-					&report.Record{
-						Name:      fmt.Sprintf("disk.%s.inodes_used_percent", v.Mountpoint),
-						Value:     usage.InodesUsedPercent,
-						Precision: 1,
-					},
-				)
-			}
+		ret := []*report.Record{
+			{Name: "mem.total", Value: float64(stat.Total), Precision: 0},
+			{Name: "mem.free", Value: float64(stat.Free), Precision: 0},
+			{Name: "mem.used", Value: float64(stat.Used), Precision: 0},
+			{Name: "mem.used_percent", Value: stat.UsedPercent, Precision: 1},
+			{Name: "mem.available", Value: float64(stat.Available), Precision: 0},
 		}
-		return ret, nil
-	}
-}
-
-func DiskioInput(args []string) func() ([]*report.Record, error) {
-	devPatterns := strings.Split(args[0], ",")
-	return func() ([]*report.Record, error) {
-		stat, err := disk.IOCounters()
-		if err != nil {
-			return nil, fmt.Errorf("inlet diskio, %s", err)
+		if stat.Total > 0 {
+			ret = append(ret, &report.Record{Name: "mem.available_percent",
+				Value: float64(stat.Available) / float64(stat.Total) * 100, Precision: 1})
 		}
-		ret := []*report.Record{}
-		for _, v := range stat {
-			matched := false
-			for _, pattern := range devPatterns {
-				if ok, err := filepath.Match(pattern, v.Name); !ok || err != nil {
-					continue
-				} else {
-					matched = true
-					break
-				}
-			}
-			if !matched {
-				continue
-			}
+		if runtime.GOOS == "linux" {
 			ret = append(ret,
-				&report.Record{
-					Name:      fmt.Sprintf("diskio.%s.read_bytes", v.Name),
-					Value:     float64(v.ReadBytes),
-					Precision: 0,
-				},
-				&report.Record{
-					Name:      fmt.Sprintf("diskio.%s.read_time", v.Name),
-					Value:     float64(v.ReadTime),
-					Precision: 0,
-				},
-				&report.Record{
-					Name:      fmt.Sprintf("diskio.%s.write_bytes", v.Name),
-					Value:     float64(v.WriteBytes),
-					Precision: 0,
-				},
-				&report.Record{
-					Name:      fmt.Sprintf("diskio.%s.write_time", v.Name),
-					Value:     float64(v.WriteTime),
-					Precision: 0,
-				},
+				&report.Record{Name: "mem.buffers", Value: float64(stat.Buffers), Precision: 0},
+				&report.Record{Name: "mem.cached", Value: float64(stat.Cached), Precision: 0},
+				&report.Record{Name: "mem.shared", Value: float64(stat.Shared), Precision: 0},
+				&report.Record{Name: "mem.dirty", Value: float64(stat.Dirty), Precision: 0},
+				&report.Record{Name: "mem.writeback", Value: float64(stat.WriteBack), Precision: 0},
+				&report.Record{Name: "mem.slab", Value: float64(stat.Slab), Precision: 0},
+				&report.Record{Name: "mem.sreclaimable", Value: float64(stat.Sreclaimable), Precision: 0},
+				&report.Record{Name: "mem.sunreclaim", Value: float64(stat.Sunreclaim), Precision: 0},
+				&report.Record{Name: "mem.page_tables", Value: float64(stat.PageTables), Precision: 0},
+				&report.Record{Name: "mem.committed_as", Value: float64(stat.CommittedAS), Precision: 0},
+				&report.Record{Name: "mem.commit_limit", Value: float64(stat.CommitLimit), Precision: 0},
+				&report.Record{Name: "mem.huge_pages_total", Value: float64(stat.HugePagesTotal), Precision: 0},
+				&report.Record{Name: "mem.huge_pages_free", Value: float64(stat.HugePagesFree), Precision: 0},
+				&report.Record{Name: "mem.huge_pages_rsvd", Value: float64(stat.HugePagesRsvd), Precision: 0},
+				&report.Record{Name: "mem.huge_page_size", Value: float64(stat.HugePageSize), Precision: 0},
+				&report.Record{Name: "mem.anon_huge_pages", Value: float64(stat.AnonHugePages), Precision: 0},
 			)
 		}
-		return ret, nil
-	}
-}
 
-func NetInput(args []string) func() ([]*report.Record, error) {
-	nicPatterns := strings.Split(args[0], ",")
-	return func() ([]*report.Record, error) {
-		stat, err := net.IOCounters(true)
+		lock.Lock()
+		defer lock.Unlock()
+		swap, err := swapMemory(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("inlet net, %s", err)
+			// logged once, not to repeat it every collection
+			if !swapFailed {
+				slog.Warn("inlet mem, swap is not available", "error", err.Error())
+				swapFailed = true
+			}
+			return ret, nil
 		}
-		ret := []*report.Record{}
-		for _, v := range stat {
-			matched := false
-			for _, pattern := range nicPatterns {
-				if ok, err := filepath.Match(pattern, v.Name); !ok || err != nil {
-					continue
-				} else {
-					matched = true
-					break
-				}
+		swapFailed = false
+		ret = append(ret,
+			&report.Record{Name: "swap.total", Value: float64(swap.Total), Precision: 0},
+			&report.Record{Name: "swap.used", Value: float64(swap.Used), Precision: 0},
+			&report.Record{Name: "swap.free", Value: float64(swap.Free), Precision: 0},
+			&report.Record{Name: "swap.used_percent", Value: swap.UsedPercent, Precision: 1},
+			&report.Record{Name: "swap.in", Value: float64(swap.Sin), Precision: 0},
+			&report.Record{Name: "swap.out", Value: float64(swap.Sout), Precision: 0},
+		)
+		now := time.Now()
+		curIn := rateSample{ts: now, value: float64(swap.Sin)}
+		curOut := rateSample{ts: now, value: float64(swap.Sout)}
+		if !prevIn.ts.IsZero() {
+			if rate, ok := counterRate(prevIn, curIn); ok {
+				ret = append(ret, &report.Record{Name: "swap.in_rate", Value: rate, Precision: 1})
 			}
-			if !matched {
-				continue
+			if rate, ok := counterRate(prevOut, curOut); ok {
+				ret = append(ret, &report.Record{Name: "swap.out_rate", Value: rate, Precision: 1})
 			}
-			ret = append(ret,
-				&report.Record{
-					Name:      fmt.Sprintf("net.%s.bytes_sent", v.Name),
-					Value:     float64(v.BytesSent),
-					Precision: 0,
-				},
-				&report.Record{
-					Name:      fmt.Sprintf("net.%s.bytes_recv", v.Name),
-					Value:     float64(v.BytesRecv),
-					Precision: 0,
-				},
-				&report.Record{
-					Name:      fmt.Sprintf("net.%s.packets_sent", v.Name),
-					Value:     float64(v.PacketsSent),
-					Precision: 0,
-				},
-				&report.Record{
-					Name:      fmt.Sprintf("net.%s.packets_recv", v.Name),
-					Value:     float64(v.PacketsRecv),
-					Precision: 0,
-				},
-				&report.Record{
-					Name:      fmt.Sprintf("net.%s.drop_in", v.Name),
-					Value:     float64(v.Dropin),
-					Precision: 0,
-				},
-				&report.Record{
-					Name:      fmt.Sprintf("net.%s.drop_out", v.Name),
-					Value:     float64(v.Dropout),
-					Precision: 0,
-				},
-			)
 		}
+		prevIn, prevOut = curIn, curOut
 		return ret, nil
 	}
 }
 
-func ProtoInput(args []string) func() ([]*report.Record, error) {
+func ProtoInput(args []string) func(context.Context) ([]*report.Record, error) {
 	protos := strings.Split(args[0], ",")
-	return func() ([]*report.Record, error) {
-		stat, err := net.ProtoCounters(protos)
+	return func(ctx context.Context) ([]*report.Record, error) {
+		stat, err := net.ProtoCountersWithContext(ctx, protos)
 		if err != nil {
 			return nil, fmt.Errorf("inlet proto, %s", err)
 		}
@@ -248,9 +236,10 @@ func ProtoInput(args []string) func() ([]*report.Record, error) {
 			for k, v := range st.Stats {
 				ret = append(ret,
 					&report.Record{
-						Name:      fmt.Sprintf("proto.%s.%s", st.Protocol, k),
+						Name:      "proto." + k,
 						Value:     float64(v),
 						Precision: 0,
+						Tags:      []report.Tag{{Key: "protocol", Value: st.Protocol}},
 					},
 				)
 			}
@@ -259,8 +248,8 @@ func ProtoInput(args []string) func() ([]*report.Record, error) {
 	}
 }
 
-func SensorInput() ([]*report.Record, error) {
-	stat, err := sensors.SensorsTemperatures()
+func SensorInput(ctx context.Context) ([]*report.Record, error) {
+	stat, err := sensors.TemperaturesWithContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("inlet sensor, %s", err)
 	}
@@ -269,20 +258,23 @@ func SensorInput() ([]*report.Record, error) {
 	for _, v := range stat {
 		ret = append(ret,
 			&report.Record{
-				Name:      fmt.Sprintf("sensor.%s.temperature", v.SensorKey),
+				Name:      "sensor.temperature",
 				Value:     v.Temperature,
 				Precision: 1,
+				Tags:      []report.Tag{{Key: "sensor", Value: v.SensorKey}},
 			},
 			/*
 				&report.Record{
-					Name:      fmt.Sprintf("sensor.%s.high", v.SensorKey),
+					Name:      "sensor.high",
 					Value:     v.High,
 					Precision: 1,
+					Tags:      []report.Tag{{Key: "sensor", Value: v.SensorKey}},
 				},
 				&report.Record{
-					Name:      fmt.Sprintf("sensor.%s.critical", v.SensorKey),
+					Name:      "sensor.critical",
 					Value:     v.Critical,
 					Precision: 1,
+					Tags:      []report.Tag{{Key: "sensor", Value: v.SensorKey}},
 				},
 			*/
 		)
@@ -290,8 +282,8 @@ func SensorInput() ([]*report.Record, error) {
 	return ret, nil
 }
 
-func HostInput() ([]*report.Record, error) {
-	stat, err := host.Info()
+func HostInput(ctx context.Context) ([]*report.Record, error) {
+	stat, err := host.InfoWithContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("inlet host, %s", err)
 	}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCpuTimesPercent(t *testing.T) {
+	last := cpu.TimesStat{CPU: "cpu0", User: 100, System: 50, Idle: 800, Iowait: 10, Steal: 0, Guest: 30}
+	cur := cpu.TimesStat{CPU: "cpu0", User: 130, System: 60, Idle: 850, Iowait: 20, Steal: 10, Guest: 60}
+	ret := cpuTimesPercent(last, cur, []report.Tag{{Key: "cpu", Value: "cpu0"}})
+
+	values := map[string]float64{}
+	for _, r := range ret {
+		values[r.Name] = r.Value
+		require.Equal(t, "cpu0", r.Tag("cpu"))
+	}
+	// the guest time is in the user time, 30+10+50+10+10 = 110
+	require.InDelta(t, 30.0/110*100, values["cpu.user_percent"], 0.001)
+	require.InDelta(t, 50.0/110*100, values["cpu.idle_percent"], 0.001)
+	require.InDelta(t, 10.0/110*100, values["cpu.iowait_percent"], 0.001)
+	require.InDelta(t, 10.0/110*100, values["cpu.steal_percent"], 0.001)
+	require.Len(t, ret, 8)
+
+	require.Nil(t, cpuTimesPercent(cur, cur, nil))
+}
+
+func TestMemInput(t *testing.T) {
+	origVirtual, origSwap := virtualMemory, swapMemory
+	defer func() { virtualMemory, swapMemory = origVirtual, origSwap }()
+	virtualMemory = func(context.Context) (*mem.VirtualMemoryStat, error) {
+		return &mem.VirtualMemoryStat{Total: 1000, Available: 250, Used: 700, Free: 300, UsedPercent: 70}, nil
+	}
+	var swapErr error
+	swapMemory = func(context.Context) (*mem.SwapMemoryStat, error) {
+		if swapErr != nil {
+			return nil, swapErr
+		}
+		return &mem.SwapMemoryStat{Total: 100, Used: 10, Free: 90, UsedPercent: 10, Sin: 4096, Sout: 8192}, nil
+	}
+
+	values := func(ret []*report.Record) map[string]float64 {
+		m := map[string]float64{}
+		for _, r := range ret {
+			m[r.Name] = r.Value
+		}
+		return m
+	}
+	input := MemInput(nil)
+	ret, err := input(context.Background())
+	require.NoError(t, err)
+	m := values(ret)
+	require.Equal(t, 1000.0, m["mem.total"])
+	require.Equal(t, 25.0, m["mem.available_percent"])
+	require.Equal(t, 8192.0, m["swap.out"])
+	require.NotContains(t, m, "swap.out_rate")
+
+	// the memory is reported without the swap
+	swapErr = errors.New("no swap")
+	ret, err = input(context.Background())
+	require.NoError(t, err)
+	m = values(ret)
+	require.Equal(t, 700.0, m["mem.used"])
+	require.NotContains(t, m, "swap.total")
+}
@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const (
+	defaultTopN         = 5
+	defaultTopMaxSeries = 50
+	// topOther is the process of the records over the cap of the series
+	topOther = "other"
+)
+
+// seriesCap limits the number of the values of a tag,
+// the values over the cap are replaced with "other".
+// The values keep their places for the life of the cap,
+// unless expireAfter is given.
+type seriesCap struct {
+	max         int
+	expireAfter time.Duration
+	seen        map[string]time.Time
+}
+
+func newSeriesCap(max int, expireAfter time.Duration) *seriesCap {
+	return &seriesCap{max: max, expireAfter: expireAfter, seen: map[string]time.Time{}}
+}
+
+func (sc *seriesCap) label(value string, now time.Time) string {
+	if _, ok := sc.seen[value]; ok || len(sc.seen) < sc.max {
+		sc.seen[value] = now
+		return value
+	}
+	return topOther
+}
+
+// expire forgets the values that have not been seen for expireAfter,
+// so that the new processes can take their places.
+// Note that the series of the forgotten values stay in the database,
+// so the number of the series grows over the cap in the long term.
+func (sc *seriesCap) expire(now time.Time) {
+	if sc.expireAfter <= 0 {
+		return
+	}
+	for k, ts := range sc.seen {
+		if now.Sub(ts) > sc.expireAfter {
+			delete(sc.seen, k)
+		}
+	}
+}
+
+// topNames returns the n names of the largest values.
+func topNames(values map[string]float64, n int) []string {
+	ret := make([]string, 0, len(values))
+	for k := range values {
+		ret = append(ret, k)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if values[ret[i]] != values[ret[j]] {
+			return values[ret[i]] > values[ret[j]]
+		}
+		return ret[i] < ret[j]
+	})
+	if len(ret) > n {
+		ret = ret[:n]
+	}
+	return ret
+}
+
+// processUsage returns the CPU percent and the RSS of the processes by name,
+// the processes of the same name are summed up. It is replaced in the tests.
+var processUsage = func(ctx context.Context, cache *procCache) (map[string]float64, map[string]float64, error) {
+	pids, err := process.PidsWithContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	cpuByName, rssByName := map[string]float64{}, map[string]float64{}
+	for _, pid := range pids {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		// the process may have exited
+		ent, err := cache.get(ctx, pid)
+		if err != nil {
+			continue
+		}
+		if v, ok := ent.cpuPercent(ctx); ok {
+			cpuByName[ent.name] += v
+		}
+		if mi, err := ent.proc.MemoryInfoWithContext(ctx); err == nil {
+			rssByName[ent.name] += float64(mi.RSS)
+		}
+	}
+	cache.sweep()
+	return cpuByName, rssByName, nil
+}
+
+// TopInput reports the top processes by CPU and by memory,
+// the processes of the same name are summed up.
+//
+//	args[0]          the number of the top processes, default 5
+//	args[1]          the cap of the process names that have been reported, default 50,
+//	                 the processes over the cap are reported as "other"
+//	expire=<dur>     the time after which a process name that has not been reported
+//	                 gives its place to another, default never
+//
+// The records are top.cpu_percent and top.memory_rss tagged with process=<name>.
+func TopInput(args []string) func(context.Context) ([]*report.Record, error) {
+	args, opts := parseOptions(args)
+	n, maxSeries := defaultTopN, defaultTopMaxSeries
+	var expireAfter time.Duration
+	var argErr error
+	if len(args) > 0 && strings.TrimSpace(args[0]) != "" {
+		if n, argErr = strconv.Atoi(strings.TrimSpace(args[0])); argErr != nil || n < 1 {
+			argErr = fmt.Errorf("inlet top, invalid number %q", args[0])
+		}
+	}
+	if len(args) > 1 && strings.TrimSpace(args[1]) != "" {
+		var err error
+		if maxSeries, err = strconv.Atoi(strings.TrimSpace(args[1])); err != nil || maxSeries < 1 {
+			argErr = fmt.Errorf("inlet top, invalid max series %q", args[1])
+		}
+	}
+
+	if v := strings.TrimSpace(opts["expire"]); v != "" {
+		var err error
+		if expireAfter, err = time.ParseDuration(v); err != nil || expireAfter < 0 {
+			argErr = fmt.Errorf("inlet top, invalid expire %q", v)
+		}
+	}
+
+	var lock sync.Mutex
+	cache := newProcCache()
+	series := newSeriesCap(maxSeries, expireAfter)
+
+	return func(ctx context.Context) ([]*report.Record, error) {
+		if argErr != nil {
+			return nil, argErr
+		}
+		lock.Lock()
+		defer lock.Unlock()
+
+		cpuByName, rssByName, err := processUsage(ctx, cache)
+		if err != nil {
+			return nil, fmt.Errorf("inlet top, %s", err)
+		}
+
+		now := time.Now()
+		series.expire(now)
+		ret := []*report.Record{}
+		emit := func(name string, values map[string]float64, precision int) {
+			// the processes over the cap are summed up as "other"
+			labels, sums := []string{}, map[string]float64{}
+			for _, k := range topNames(values, n) {
+				label := series.label(k, now)
+				if _, ok := sums[label]; !ok {
+					labels = append(labels, label)
+				}
+				sums[label] += values[k]
+			}
+			for _, label := range labels {
+				ret = append(ret, &report.Record{Name: name, Value: sums[label], Precision: precision,
+					Tags: []report.Tag{{Key: "process", Value: label}}})
+			}
+		}
+		emit("top.cpu_percent", cpuByName, 1)
+		emit("top.memory_rss", rssByName, 0)
+		return ret, nil
+	}
+}
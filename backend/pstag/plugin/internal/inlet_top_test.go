@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopNames(t *testing.T) {
+	values := map[string]float64{"neo": 30, "java": 50, "sshd": 0.1, "bash": 30}
+	require.Equal(t, []string{"java", "bash", "neo"}, topNames(values, 3))
+	require.Len(t, topNames(values, 10), 4)
+}
+
+func TestSeriesCap(t *testing.T) {
+	now := time.Now()
+	sc := newSeriesCap(2, 0)
+	require.Equal(t, "neo", sc.label("neo", now))
+	require.Equal(t, "java", sc.label("java", now))
+	require.Equal(t, topOther, sc.label("bash", now))
+	require.Equal(t, "neo", sc.label("neo", now))
+
+	// the names keep their places without the expiry
+	sc.expire(now.Add(24 * time.Hour))
+	require.Equal(t, topOther, sc.label("bash", now))
+
+	// the process that has gone makes room for the new one
+	sc = newSeriesCap(2, time.Hour)
+	sc.label("neo", now)
+	sc.label("java", now.Add(time.Hour))
+	sc.expire(now.Add(time.Hour + time.Second))
+	require.Equal(t, "bash", sc.label("bash", now))
+	require.Equal(t, topOther, sc.label("neo", now))
+}
+
+func TestTopInput(t *testing.T) {
+	defer func(orig func(context.Context, *procCache) (map[string]float64, map[string]float64, error)) {
+		processUsage = orig
+	}(processUsage)
+	var cpu, rss map[string]float64
+	processUsage = func(context.Context, *procCache) (map[string]float64, map[string]float64, error) {
+		return cpu, rss, nil
+	}
+	collect := func(input func(context.Context) ([]*report.Record, error)) map[string]float64 {
+		ret, err := input(context.Background())
+		require.NoError(t, err)
+		values := map[string]float64{}
+		for _, r := range ret {
+			values[r.Name+":"+r.Tag("process")] = r.Value
+		}
+		return values
+	}
+
+	input := TopInput([]string{"2", "3"})
+	cpu = map[string]float64{"neo": 30, "java": 50, "sshd": 0.1}
+	rss = map[string]float64{"neo": 300, "java": 100, "bash": 200}
+	values := collect(input)
+	require.Equal(t, map[string]float64{
+		"top.cpu_percent:java": 50, "top.cpu_percent:neo": 30,
+		"top.memory_rss:neo": 300, "top.memory_rss:bash": 200,
+	}, values)
+
+	// the new names over the cap of 3 are reported as other
+	cpu = map[string]float64{"python": 90, "node": 80}
+	rss = map[string]float64{"neo": 300}
+	values = collect(input)
+	require.Equal(t, map[string]float64{"top.cpu_percent:other": 170, "top.memory_rss:neo": 300}, values)
+
+	_, err := TopInput([]string{"zero"})(context.Background())
+	require.Error(t, err)
+	_, err = TopInput([]string{"5", "expire=often"})(context.Background())
+	require.Error(t, err)
+}
@@ -9,8 +9,13 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 )
 
+// neoHttpTimeout bounds a request to the machbase-neo,
+// in addition to the deadline of the collection.
+const neoHttpTimeout = 10 * time.Second
+
 var neoHttpClient *NeoHttpClient
 var neoHttpClientOnce sync.Once
 
@@ -26,12 +31,12 @@ func DefaultNeoHttpClient() *NeoHttpClient {
 
 func InitNeoHttpClient(addr string) {
 	neoHttpClientOnce.Do(func() {
-		neoHttpClient = &NeoHttpClient{Client: &http.Client{}}
+		neoHttpClient = &NeoHttpClient{Client: &http.Client{Timeout: neoHttpTimeout}}
 		if strings.HasPrefix(addr, "unix://") {
 			path := addr[7:]
 			neoHttpClient.Client.Transport = &http.Transport{
 				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-					ret, err := net.Dial("unix", path)
+					ret, err := (&net.Dialer{}).DialContext(ctx, "unix", path)
 					if err != nil {
 						fmt.Println("Failed to dial", err)
 					}
@@ -40,7 +45,7 @@ func InitNeoHttpClient(addr string) {
 			}
 			neoHttpClient.host = "http://local.local"
 		} else {
-			neoHttpClient.Client = &http.Client{}
+			neoHttpClient.Client = &http.Client{Timeout: neoHttpTimeout}
 			neoHttpClient.host = strings.Replace(addr, "tcp://", "http://", 1)
 		}
 	})
@@ -57,13 +62,17 @@ type NeoQueryResponse struct {
 	} `json:"data"`
 }
 
-func (c *NeoHttpClient) GetCountTable(table string) (*NeoQueryResponse, error) {
+func (c *NeoHttpClient) GetCountTable(ctx context.Context, table string) (*NeoQueryResponse, error) {
 	// neoHttpClient.Lock()
 	// defer neoHttpClient.Unlock()
 
 	query := fmt.Sprintf("SELECT count(*) FROM %s", table)
 	path, _ := url.JoinPath(c.host, "/db/query")
-	rsp, err := neoHttpClient.Get(path + "?q=" + url.QueryEscape(query))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path+"?q="+url.QueryEscape(query), nil)
+	if err != nil {
+		return nil, fmt.Errorf("table_rows_counter %s", err)
+	}
+	rsp, err := neoHttpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("table_rows_counter %s", err)
 	}
@@ -75,12 +84,16 @@ func (c *NeoHttpClient) GetCountTable(table string) (*NeoQueryResponse, error) {
 	return o, nil
 }
 
-func (c *NeoHttpClient) GetStatz() (*NeoStatz, error) {
+func (c *NeoHttpClient) GetStatz(ctx context.Context) (*NeoStatz, error) {
 	// neoHttpClient.Lock()
 	// defer neoHttpClient.Unlock()
 
 	path, _ := url.JoinPath(c.host, "/db/statz")
-	rsp, err := c.Client.Get(path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("statz %s", err)
+	}
+	rsp, err := c.Client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("statz %s", err)
 	}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"neo-cat/backend/pstag/report"
+)
+
+// parseOptions splits the args into the positional args and
+// the "key=value" options that follow them.
+func parseOptions(args []string) ([]string, map[string]string) {
+	positional := []string{}
+	opts := map[string]string{}
+	for _, arg := range args {
+		if k, v, ok := strings.Cut(arg, "="); ok && isOptionKey(k) {
+			opts[k] = v
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+	return positional, opts
+}
+
+func isOptionKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, c := range k {
+		if !(c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// outletFormat is how an outlet writes a record into a csv row.
+//
+//	name_template=<tmpl>  the template that flattens the tags into the name, see report.Naming
+//	tags=json             append the tags as a JSON column, for the table that has the column
+type outletFormat struct {
+	naming   *report.Naming
+	tagsJson bool
+}
+
+func newOutletFormat(opts map[string]string) *outletFormat {
+	return &outletFormat{
+		naming:   report.NewNaming(opts["name_template"]),
+		tagsJson: opts["tags"] == "json",
+	}
+}
+
+func (f *outletFormat) row(rec *report.Record, strTs string) []string {
+	strVal := strconv.FormatFloat(rec.Value, 'f', rec.Precision, 64)
+	ret := []string{f.naming.Name(rec), strTs, strVal}
+	if f.tagsJson {
+		tags := map[string]string{}
+		for _, t := range rec.Tags {
+			tags[t.Key] = t.Value
+		}
+		b, _ := json.Marshal(tags)
+		ret = append(ret, string(b))
+	}
+	return ret
+}
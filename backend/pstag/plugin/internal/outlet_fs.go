@@ -11,11 +11,13 @@ import (
 )
 
 func NewFileOutlet(args ...string) report.Outlet {
-	return &FileOutlet{path: args[0]}
+	args, opts := parseOptions(args)
+	return &FileOutlet{path: args[0], format: newOutletFormat(opts)}
 }
 
 type FileOutlet struct {
 	path   string
+	format *outletFormat
 	w      *csv.Writer
 	closer io.Closer
 }
@@ -27,6 +29,7 @@ func (fo *FileOutlet) Open() error {
 	} else {
 		if f, err := os.OpenFile(fo.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
 			slog.Error("failed to open file", "path", fo.path, "error", err.Error())
+			return err
 		} else {
 			out = f
 			fo.closer = f
@@ -47,10 +50,9 @@ func (fo *FileOutlet) Handle(recs []*report.Report) error {
 	for _, r := range recs {
 		strTs := strconv.FormatInt(r.Ts.Unix(), 10)
 		for _, rec := range r.Records {
-			strVal := strconv.FormatFloat(rec.Value, 'f', rec.Precision, 64)
-			fo.w.Write([]string{rec.Name, strTs, strVal})
+			fo.w.Write(fo.format.row(rec, strTs))
 		}
 	}
 	fo.w.Flush()
-	return nil
+	return fo.w.Error()
 }
@@ -3,23 +3,28 @@ package internal
 import (
 	"bytes"
 	"encoding/csv"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"neo-cat/backend/pstag/report"
 )
 
 type HttpOutlet struct {
 	addr   string
+	format *outletFormat
 	client http.Client
 }
 
 func NewHttpOutlet(args ...string) report.Outlet {
+	args, opts := parseOptions(args)
 	return &HttpOutlet{
 		addr:   args[0],
-		client: http.Client{},
+		format: newOutletFormat(opts),
+		client: http.Client{Timeout: 10 * time.Second},
 	}
 }
 
@@ -38,21 +43,23 @@ func (ho *HttpOutlet) Handle(recs []*report.Report) error {
 	for _, r := range recs {
 		strTs := strconv.FormatInt(r.Ts.Unix(), 10)
 		for _, rec := range r.Records {
-			strVal := strconv.FormatFloat(rec.Value, 'f', rec.Precision, 64)
-			w.Write([]string{rec.Name, strTs, strVal})
+			w.Write(ho.format.row(rec, strTs))
 		}
 	}
 	w.Flush()
 
 	rsp, err := ho.client.Post(ho.addr, "text/csv", data)
 	if err != nil {
-		return err
+		return fmt.Errorf("out-http %s", err)
 	}
 	defer rsp.Body.Close()
 	body, err := io.ReadAll(rsp.Body)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("out-http %s", err)
 	}
-	slog.Info("out-http", "status", rsp.Status, "response", string(body))
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return fmt.Errorf("out-http %s %s", rsp.Status, string(body))
+	}
+	slog.Debug("out-http", "status", rsp.Status, "response", string(body))
 	return nil
 }
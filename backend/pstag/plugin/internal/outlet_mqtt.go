@@ -3,10 +3,12 @@ package internal
 import (
 	"bytes"
 	"encoding/csv"
-	"log/slog"
+	"fmt"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"neo-cat/backend/pstag/report"
@@ -14,8 +16,13 @@ import (
 	paho "github.com/eclipse/paho.mqtt.golang"
 )
 
+// mqttClientSeq makes the client ids unique,
+// the broker disconnects the older client of the same id.
+var mqttClientSeq atomic.Int64
+
 type MqttOutlet struct {
 	addr    string
+	format  *outletFormat
 	host    string
 	topic   string
 	qos     byte
@@ -24,8 +31,10 @@ type MqttOutlet struct {
 }
 
 func NewMqttOutlet(args ...string) report.Outlet {
+	args, opts := parseOptions(args)
 	return &MqttOutlet{
 		addr:    args[0],
+		format:  newOutletFormat(opts),
 		qos:     1,
 		timeout: 3 * time.Second,
 	}
@@ -41,10 +50,12 @@ func (ho *MqttOutlet) Open() error {
 
 	opts := paho.NewClientOptions()
 	opts.SetCleanSession(true)
-	opts.SetConnectRetry(false)
+	// keep trying the initial connection in background,
+	// Handle() fails until the broker is reachable.
+	opts.SetConnectRetry(true)
 	opts.SetAutoReconnect(true)
 	opts.SetProtocolVersion(4)
-	opts.SetClientID("pstag")
+	opts.SetClientID(fmt.Sprintf("pstag-%d-%d", os.Getpid(), mqttClientSeq.Add(1)))
 	opts.AddBroker(ho.host)
 	opts.SetKeepAlive(60 * time.Second)
 
@@ -70,16 +81,20 @@ func (ho *MqttOutlet) Handle(recs []*report.Report) error {
 	for _, r := range recs {
 		strTs := strconv.FormatInt(r.Ts.UnixNano(), 10)
 		for _, rec := range r.Records {
-			strVal := strconv.FormatFloat(rec.Value, 'f', rec.Precision, 64)
-			w.Write([]string{rec.Name, strTs, strVal})
+			w.Write(ho.format.row(rec, strTs))
 		}
 	}
 	w.Flush()
 
+	if !ho.client.IsConnectionOpen() {
+		return fmt.Errorf("out-mqtt not connected to %s", ho.host)
+	}
 	tok := ho.client.Publish(ho.topic, ho.qos, false, data.Bytes())
-	tok.WaitTimeout(ho.timeout)
+	if !tok.WaitTimeout(ho.timeout) {
+		return fmt.Errorf("out-mqtt publish timeout")
+	}
 	if tok.Error() != nil {
-		slog.Error("out-mqtt", "error", tok.Error().Error())
+		return fmt.Errorf("out-mqtt %s", tok.Error())
 	}
 	return nil
 }
@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+)
+
+var aggregateStats = []string{"min", "max", "avg", "last", "count", "p95"}
+
+type aggSeries struct {
+	name      string
+	tags      []report.Tag
+	precision int
+	bucket    time.Time
+	values    []float64
+}
+
+// AggregateProcessor summarizes the matched records over the window
+// that is aligned to the wall-clock, e.g. :00, :10, :20 for 10s.
+// It emits a record per statistic with the suffix of the statistic,
+// e.g. cpu.percent_avg, stamped with the start of the window.
+// The matched records are not passed through.
+//
+//	args[0]  glob patterns of the records, comma(,) separated
+//	args[1]  window, e.g. 10s, 1m
+//	args[2]  statistics, comma(,) separated, default "min,max,avg,last,count"
+//	         available: min,max,avg,last,count,p95
+//
+// The window is emitted when a later record arrives,
+// the returned flush emits the windows that are not completed yet.
+func AggregateProcessor(args []string) (func([]*report.Report) ([]*report.Report, error), func() []*report.Report) {
+	if len(args) < 2 {
+		return failProcessor(fmt.Errorf("proc-aggregate, requires <glob> <window>")), nil
+	}
+	patterns := splitPatterns(args[:1])
+	window, err := time.ParseDuration(strings.TrimSpace(args[1]))
+	if err != nil || window <= 0 {
+		return failProcessor(fmt.Errorf("proc-aggregate, invalid window %q", args[1])), nil
+	}
+	stats := []string{"min", "max", "avg", "last", "count"}
+	if len(args) > 2 {
+		stats = splitPatterns(args[2:3])
+		for _, st := range stats {
+			if !slices.Contains(aggregateStats, st) {
+				return failProcessor(fmt.Errorf("proc-aggregate, unknown statistic %q", st)), nil
+			}
+		}
+	}
+	series := map[string]*aggSeries{}
+	order := []string{}
+	summaries := map[time.Time]*report.Report{}
+
+	emit := func(s *aggSeries) {
+		if len(s.values) == 0 {
+			return
+		}
+		rpt, ok := summaries[s.bucket]
+		if !ok {
+			rpt = &report.Report{Ts: s.bucket}
+			summaries[s.bucket] = rpt
+		}
+		for _, st := range stats {
+			rpt.Records = append(rpt.Records, &report.Record{
+				Name:      s.name + "_" + st,
+				Value:     aggregate(st, s.values),
+				Precision: aggregatePrecision(st, s.precision),
+				Tags:      append([]report.Tag(nil), s.tags...),
+			})
+		}
+		s.values = s.values[:0]
+	}
+	// emitted returns the emitted summaries in the order of the windows
+	emitted := func() []*report.Report {
+		buckets := make([]time.Time, 0, len(summaries))
+		for ts := range summaries {
+			buckets = append(buckets, ts)
+		}
+		slices.SortFunc(buckets, func(a, b time.Time) int { return a.Compare(b) })
+		ret := make([]*report.Report, 0, len(buckets))
+		for _, ts := range buckets {
+			ret = append(ret, summaries[ts])
+		}
+		clear(summaries)
+		return ret
+	}
+
+	handle := func(rpts []*report.Report) ([]*report.Report, error) {
+		var now time.Time
+		for _, r := range rpts {
+			if r.Ts.After(now) {
+				now = r.Ts
+			}
+			bucket := r.Ts.Truncate(window)
+			recs := r.Records[:0]
+			for _, rec := range r.Records {
+				if !matchPatterns(patterns, rec.Name) {
+					recs = append(recs, rec)
+					continue
+				}
+				key := seriesKey(rec)
+				s, ok := series[key]
+				if !ok {
+					s = &aggSeries{name: rec.Name, tags: rec.Tags, bucket: bucket}
+					series[key] = s
+					order = append(order, key)
+				}
+				if !s.bucket.Equal(bucket) {
+					emit(s)
+					s.bucket = bucket
+				}
+				s.precision = rec.Precision
+				s.values = append(s.values, rec.Value)
+			}
+			r.Records = recs
+		}
+		// the windows that have been passed are emitted,
+		// even though their series have no new records.
+		if !now.IsZero() {
+			current := now.Truncate(window)
+			alive := order[:0]
+			for _, key := range order {
+				s := series[key]
+				if s.bucket.Before(current) {
+					emit(s)
+					if now.Sub(s.bucket) > staleAfter {
+						delete(series, key)
+						continue
+					}
+				}
+				alive = append(alive, key)
+			}
+			order = alive
+		}
+		ret := filterRecords(rpts, func(*report.Record) bool { return true })
+		return append(ret, emitted()...), nil
+	}
+	flush := func() []*report.Report {
+		for _, key := range order {
+			emit(series[key])
+		}
+		return emitted()
+	}
+	return handle, flush
+}
+
+func aggregate(stat string, values []float64) float64 {
+	switch stat {
+	case "min":
+		return slices.Min(values)
+	case "max":
+		return slices.Max(values)
+	case "avg":
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	case "last":
+		return values[len(values)-1]
+	case "count":
+		return float64(len(values))
+	case "p95":
+		sorted := slices.Clone(values)
+		slices.Sort(sorted)
+		idx := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+		return sorted[max(idx, 0)]
+	}
+	return math.NaN()
+}
+
+func aggregatePrecision(stat string, precision int) int {
+	switch stat {
+	case "count":
+		return 0
+	case "avg":
+		return max(precision, 2)
+	}
+	return precision
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"neo-cat/backend/pstag/report"
+)
+
+func IncludeProcessor(args []string) func([]*report.Report) ([]*report.Report, error) {
+	patterns := splitPatterns(args)
+	return func(rpts []*report.Report) ([]*report.Report, error) {
+		return filterRecords(rpts, func(rec *report.Record) bool {
+			return matchPatterns(patterns, rec.Name)
+		}), nil
+	}
+}
+
+func ExcludeProcessor(args []string) func([]*report.Report) ([]*report.Report, error) {
+	patterns := splitPatterns(args)
+	return func(rpts []*report.Report) ([]*report.Report, error) {
+		return filterRecords(rpts, func(rec *report.Record) bool {
+			return !matchPatterns(patterns, rec.Name)
+		}), nil
+	}
+}
+
+func RenameProcessor(args []string) func([]*report.Report) ([]*report.Report, error) {
+	if len(args) < 2 {
+		return failProcessor(fmt.Errorf("proc-rename, requires <regexp> <replacement>"))
+	}
+	re, err := regexp.Compile(args[0])
+	if err != nil {
+		return failProcessor(fmt.Errorf("proc-rename, %s", err))
+	}
+	repl := args[1]
+	return func(rpts []*report.Report) ([]*report.Report, error) {
+		for _, r := range rpts {
+			for _, rec := range r.Records {
+				rec.Name = re.ReplaceAllString(rec.Name, repl)
+			}
+		}
+		return rpts, nil
+	}
+}
+
+func ScaleProcessor(args []string) func([]*report.Report) ([]*report.Report, error) {
+	if len(args) < 2 {
+		return failProcessor(fmt.Errorf("proc-scale, requires <glob> <factor>"))
+	}
+	patterns := splitPatterns(args[:1])
+	factor, err := strconv.ParseFloat(strings.TrimSpace(args[1]), 64)
+	if err != nil {
+		return failProcessor(fmt.Errorf("proc-scale, %s", err))
+	}
+	return func(rpts []*report.Report) ([]*report.Report, error) {
+		for _, r := range rpts {
+			for _, rec := range r.Records {
+				if matchPatterns(patterns, rec.Name) {
+					rec.Value *= factor
+				}
+			}
+		}
+		return rpts, nil
+	}
+}
+
+func DropNaNProcessor(args []string) func([]*report.Report) ([]*report.Report, error) {
+	return func(rpts []*report.Report) ([]*report.Report, error) {
+		return filterRecords(rpts, func(rec *report.Record) bool {
+			return !math.IsNaN(rec.Value) && !math.IsInf(rec.Value, 0)
+		}), nil
+	}
+}
+
+// failProcessor passes the reports through and reports the error of the arguments.
+func failProcessor(err error) func([]*report.Report) ([]*report.Report, error) {
+	return func(rpts []*report.Report) ([]*report.Report, error) {
+		return rpts, err
+	}
+}
+
+// filterRecords keeps the records that keep() returns true,
+// the reports that have no records left are dropped.
+func filterRecords(rpts []*report.Report, keep func(*report.Record) bool) []*report.Report {
+	ret := rpts[:0]
+	for _, r := range rpts {
+		recs := r.Records[:0]
+		for _, rec := range r.Records {
+			if keep(rec) {
+				recs = append(recs, rec)
+			}
+		}
+		r.Records = recs
+		if len(r.Records) > 0 {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+func splitPatterns(args []string) []string {
+	ret := []string{}
+	for _, arg := range args {
+		for _, p := range strings.Split(arg, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				ret = append(ret, p)
+			}
+		}
+	}
+	return ret
+}
+
+func matchPatterns(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, err := filepath.Match(pattern, name); ok && err == nil {
+			return true
+		}
+	}
+	return false
+}
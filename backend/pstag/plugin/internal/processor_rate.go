@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+)
+
+type rateSample struct {
+	ts    time.Time
+	value float64
+}
+
+// RateProcessor derives the per-second rates of the cumulative counters
+// from the previous sample of the same series (name and tags).
+//
+//	args[0]  glob patterns of the counters, comma(,) separated
+//	args[1]  "add" (default) emits <name>_rate next to the counter,
+//	         "replace" emits the rate instead of the counter
+//	suffix=        the suffix of the rate record, default "_rate"
+//	counter_bits=  the width of the counters, 32 or 64
+//
+// When a counter goes backward, it is taken as a reset (e.g. reboot or restart)
+// and no rate is emitted for the sample. Only if counter_bits is given,
+// it is taken as a wraparound of the counter of the width.
+func RateProcessor(args []string) func([]*report.Report) ([]*report.Report, error) {
+	args, opts := parseOptions(args)
+	patterns := splitPatterns(args[:min(1, len(args))])
+	replace := len(args) > 1 && strings.TrimSpace(args[1]) == "replace"
+	suffix := "_rate"
+	if v, ok := opts["suffix"]; ok {
+		suffix = v
+	}
+	bits, _ := strconv.Atoi(strings.TrimSpace(opts["counter_bits"]))
+	prev := map[string]rateSample{}
+
+	return func(rpts []*report.Report) ([]*report.Report, error) {
+		var now time.Time
+		for _, r := range rpts {
+			if r.Ts.After(now) {
+				now = r.Ts
+			}
+			recs := make([]*report.Record, 0, len(r.Records))
+			for _, rec := range r.Records {
+				if !matchPatterns(patterns, rec.Name) {
+					recs = append(recs, rec)
+					continue
+				}
+				if !replace {
+					recs = append(recs, rec)
+				}
+				key := seriesKey(rec)
+				last, ok := prev[key]
+				prev[key] = rateSample{ts: r.Ts, value: rec.Value}
+				if !ok {
+					continue
+				}
+				rate, ok := wrapRate(last, rateSample{ts: r.Ts, value: rec.Value}, bits)
+				if !ok {
+					continue
+				}
+				recs = append(recs, &report.Record{
+					Name:      rec.Name + suffix,
+					Value:     rate,
+					Precision: max(rec.Precision, 2),
+					Tags:      append([]report.Tag(nil), rec.Tags...),
+				})
+			}
+			r.Records = recs
+		}
+		for k, v := range prev {
+			if now.Sub(v.ts) > staleAfter {
+				delete(prev, k)
+			}
+		}
+		return filterRecords(rpts, func(*report.Record) bool { return true }), nil
+	}
+}
+
+// counterRate returns the per-second rate between two samples of a counter.
+// A counter that goes backward is taken as a reset and no rate is returned.
+func counterRate(prev, cur rateSample) (float64, bool) {
+	return wrapRate(prev, cur, 0)
+}
+
+// wrapRate is counterRate of the counter of the width in bits (32 or 64),
+// that a counter going backward is taken as a wraparound.
+// The bits of 0 is the unknown width, that is counterRate.
+func wrapRate(prev, cur rateSample, bits int) (float64, bool) {
+	dt := cur.ts.Sub(prev.ts).Seconds()
+	if dt <= 0 {
+		return 0, false
+	}
+	delta := cur.value - prev.value
+	if delta < 0 {
+		switch {
+		case bits == 32 && prev.value <= math.MaxUint32:
+			delta = math.MaxUint32 - prev.value + cur.value + 1
+		case bits == 64:
+			delta = math.MaxUint64 - prev.value + cur.value + 1
+		default:
+			// reset
+			return 0, false
+		}
+	}
+	return delta / dt, true
+}
+
+// seriesKey identifies a series by the name and the tags of the record.
+func seriesKey(rec *report.Record) string {
+	if len(rec.Tags) == 0 {
+		return rec.Name
+	}
+	sb := strings.Builder{}
+	sb.WriteString(rec.Name)
+	for _, t := range rec.Tags {
+		sb.WriteString("\x00")
+		sb.WriteString(t.Key)
+		sb.WriteString("=")
+		sb.WriteString(t.Value)
+	}
+	return sb.String()
+}
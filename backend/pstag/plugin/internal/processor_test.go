@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/stretchr/testify/require"
+)
+
+func names(rpts []*report.Report) []string {
+	ret := []string{}
+	for _, r := range rpts {
+		for _, rec := range r.Records {
+			ret = append(ret, rec.Name)
+		}
+	}
+	return ret
+}
+
+func newReport(ts time.Time, recs ...*report.Record) []*report.Report {
+	return []*report.Report{{Ts: ts, Records: recs}}
+}
+
+func TestFilterProcessors(t *testing.T) {
+	ts := time.Now()
+	input := func() []*report.Report {
+		return newReport(ts,
+			&report.Record{Name: "cpu.percent", Value: 10},
+			&report.Record{Name: "mem.used", Value: 1024},
+			&report.Record{Name: "disk.used", Value: math.NaN()},
+		)
+	}
+
+	ret, err := IncludeProcessor([]string{"cpu.*,mem.*"})(input())
+	require.NoError(t, err)
+	require.Equal(t, []string{"cpu.percent", "mem.used"}, names(ret))
+
+	ret, err = ExcludeProcessor([]string{"cpu.*"})(input())
+	require.NoError(t, err)
+	require.Equal(t, []string{"mem.used", "disk.used"}, names(ret))
+
+	ret, err = ExcludeProcessor([]string{"*"})(input())
+	require.NoError(t, err)
+	require.Empty(t, ret)
+
+	ret, err = RenameProcessor([]string{`^mem\.(.*)$`, "memory.$1"})(input())
+	require.NoError(t, err)
+	require.Equal(t, []string{"cpu.percent", "memory.used", "disk.used"}, names(ret))
+
+	ret, err = ScaleProcessor([]string{"mem.*", "0.5"})(input())
+	require.NoError(t, err)
+	require.Equal(t, 512.0, ret[0].Records[1].Value)
+
+	ret, err = DropNaNProcessor(nil)(input())
+	require.NoError(t, err)
+	require.Equal(t, []string{"cpu.percent", "mem.used"}, names(ret))
+
+	_, err = RenameProcessor([]string{"("})(input())
+	require.Error(t, err)
+}
+
+func TestRateProcessor(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	proc := RateProcessor([]string{"net.*"})
+	counter := func(v float64) *report.Record {
+		return &report.Record{Name: "net.bytes_sent", Value: v, Tags: []report.Tag{{Key: "interface", Value: "eth0"}}}
+	}
+
+	// first sample has no rate
+	ret, _ := proc(newReport(ts, counter(1000)))
+	require.Equal(t, []string{"net.bytes_sent"}, names(ret))
+
+	ret, _ = proc(newReport(ts.Add(10*time.Second), counter(3000)))
+	require.Equal(t, []string{"net.bytes_sent", "net.bytes_sent_rate"}, names(ret))
+	require.Equal(t, 200.0, ret[0].Records[1].Value)
+	require.Equal(t, "eth0", ret[0].Records[1].Tag("interface"))
+
+	// reset, no rate
+	ret, _ = proc(newReport(ts.Add(20*time.Second), counter(100)))
+	require.Equal(t, []string{"net.bytes_sent"}, names(ret))
+
+	// reset of a 64bit counter from above 2^31 is not a wraparound
+	ret, _ = proc(newReport(ts.Add(30*time.Second), counter(3e9)))
+	require.Len(t, ret[0].Records, 2)
+	ret, _ = proc(newReport(ts.Add(40*time.Second), counter(900)))
+	require.Equal(t, []string{"net.bytes_sent"}, names(ret))
+
+	// 32bit wraparound
+	proc = RateProcessor([]string{"net.*", "counter_bits=32"})
+	ret, _ = proc(newReport(ts.Add(30*time.Second), counter(math.MaxUint32-99)))
+	require.Len(t, ret[0].Records, 1)
+	ret, _ = proc(newReport(ts.Add(40*time.Second), counter(900)))
+	require.Equal(t, 100.0, ret[0].Records[1].Value)
+
+	// replace mode
+	proc = RateProcessor([]string{"net.*", "replace"})
+	ret, _ = proc(newReport(ts, counter(0), &report.Record{Name: "cpu.percent"}))
+	require.Equal(t, []string{"cpu.percent"}, names(ret))
+	ret, _ = proc(newReport(ts.Add(time.Second), counter(10)))
+	require.Equal(t, []string{"net.bytes_sent_rate"}, names(ret))
+	require.Equal(t, 10.0, ret[0].Records[0].Value)
+}
+
+func TestAggregateProcessor(t *testing.T) {
+	ts := time.Unix(1700000000, 0) // aligned to 10s
+	proc, flush := AggregateProcessor([]string{"cpu.*", "10s", "min,max,avg,count,p95"})
+
+	for i := 0; i < 10; i++ {
+		ret, err := proc(newReport(ts.Add(time.Duration(i)*time.Second),
+			&report.Record{Name: "cpu.percent", Value: float64(i + 1), Precision: 1},
+			&report.Record{Name: "mem.used", Value: 1},
+		))
+		require.NoError(t, err)
+		require.Equal(t, []string{"mem.used"}, names(ret))
+	}
+	ret, err := proc(newReport(ts.Add(10*time.Second), &report.Record{Name: "mem.used", Value: 1}))
+	require.NoError(t, err)
+	require.Len(t, ret, 2)
+	require.Equal(t, []string{"mem.used", "cpu.percent_min", "cpu.percent_max", "cpu.percent_avg", "cpu.percent_count", "cpu.percent_p95"}, names(ret))
+	require.True(t, ts.Equal(ret[1].Ts))
+	values := []float64{}
+	for _, rec := range ret[1].Records {
+		values = append(values, rec.Value)
+	}
+	require.Equal(t, []float64{1, 10, 5.5, 10, 10}, values)
+
+	// the partial window is emitted by the flush
+	_, err = proc(newReport(ts.Add(11*time.Second), &report.Record{Name: "cpu.percent", Value: 3}))
+	require.NoError(t, err)
+	ret = flush()
+	require.Len(t, ret, 1)
+	require.True(t, ts.Add(10*time.Second).Equal(ret[0].Ts))
+	require.Equal(t, 1.0, ret[0].Records[3].Value)
+	require.Empty(t, flush())
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// staleAfter is the time after which the state of a series, a device
+// or an interface that has not been seen is forgotten.
+const staleAfter = time.Hour
+
+// readFlatKeyed reads the file of "<key> <value>" lines,
+// e.g. cpu.stat, memory.events of cgroup and /proc/vmstat.
+// The lines of non-numeric value are skipped.
+func readFlatKeyed(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ret := map[string]float64{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			ret[fields[0]] = v
+		}
+	}
+	return ret, sc.Err()
+}
+
+// readValue reads the file of a single value, e.g. memory.current of cgroup.
+// It returns false for "max" that is no limit.
+func readValue(path string) (float64, bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false, err
+	}
+	str := strings.TrimSpace(string(b))
+	if str == "max" {
+		return 0, false, nil
+	}
+	v, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return v, true, nil
+}
+
+// readPressure reads the pressure stall information of a resource,
+// e.g. /proc/pressure/cpu or cpu.pressure of cgroup.
+//
+//	some avg10=0.12 avg60=0.05 avg300=0.01 total=123456
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func readPressure(path string) (map[string]map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ret := map[string]map[string]float64{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		values := map[string]float64{}
+		for _, field := range fields[1:] {
+			if k, v, ok := strings.Cut(field, "="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					values[k] = f
+				}
+			}
+		}
+		ret[fields[0]] = values
+	}
+	return ret, sc.Err()
+}
@@ -1,6 +1,8 @@
 package plugin
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -11,11 +13,14 @@ import (
 
 type InletFactory func(args ...string) report.Inlet
 type OutletFactory func(args ...string) report.Outlet
+type ProcessorFactory func(args ...string) report.Processor
 
 var inletRegistry map[string]*InletReg = make(map[string]*InletReg)
 var outletRegistry map[string]*OutletReg = make(map[string]*OutletReg)
+var processorRegistry map[string]*ProcessorReg = make(map[string]*ProcessorReg)
 var inletNames = []string{}
 var outletNames = []string{}
+var processorNames = []string{}
 var regLock = sync.Mutex{}
 
 type InletReg struct {
@@ -23,6 +28,7 @@ type InletReg struct {
 	Factory    InletFactory
 	ArgDefault any
 	ArgDesc    string
+	Args       ArgSpecs
 }
 
 type OutletReg struct {
@@ -30,6 +36,15 @@ type OutletReg struct {
 	Factory    OutletFactory
 	ArgDefault any
 	ArgDesc    string
+	Args       ArgSpecs
+}
+
+type ProcessorReg struct {
+	Name       string
+	Factory    ProcessorFactory
+	ArgDefault any
+	ArgDesc    string
+	Args       ArgSpecs
 }
 
 func RegisterInlet(reg *InletReg) {
@@ -39,12 +54,13 @@ func RegisterInlet(reg *InletReg) {
 	inletNames = append(inletNames, reg.Name)
 }
 
-func RegisterInletWith(name string, factory InletFactory, argDefault any, argDesc string) {
+func RegisterInletWith(name string, factory InletFactory, argDefault any, argDesc string, args ...ArgSpec) {
 	RegisterInlet(&InletReg{
 		Name:       name,
 		Factory:    factory,
 		ArgDefault: argDefault,
 		ArgDesc:    argDesc,
+		Args:       args,
 	})
 }
 
@@ -55,29 +71,89 @@ func RegisterOutlet(reg *OutletReg) {
 	outletNames = append(outletNames, reg.Name)
 }
 
-func RegisterOutletWith(name string, factory OutletFactory, argDefault any, argDesc string) {
+func RegisterOutletWith(name string, factory OutletFactory, argDefault any, argDesc string, args ...ArgSpec) {
 	RegisterOutlet(&OutletReg{
 		Name:       name,
 		Factory:    factory,
 		ArgDefault: argDefault,
 		ArgDesc:    argDesc,
+		Args:       args,
+	})
+}
+
+func RegisterProcessor(reg *ProcessorReg) {
+	regLock.Lock()
+	defer regLock.Unlock()
+	processorRegistry[reg.Name] = reg
+	processorNames = append(processorNames, reg.Name)
+}
+
+func RegisterProcessorWith(name string, factory ProcessorFactory, argDefault any, argDesc string, args ...ArgSpec) {
+	RegisterProcessor(&ProcessorReg{
+		Name:       name,
+		Factory:    factory,
+		ArgDefault: argDefault,
+		ArgDesc:    argDesc,
+		Args:       args,
 	})
 }
 
+// NewInlet creates the inlet of the name, or nil if it is unknown.
+// If the args do not match the schema, the inlet fails to open.
 func NewInlet(name string, args ...string) report.Inlet {
 	if reg, ok := inletRegistry[name]; ok {
+		if err := reg.Args.Validate(args); err != nil {
+			return &failPlugin{err: fmt.Errorf("inlet %s, %s", name, err)}
+		}
 		return reg.Factory(args...)
 	}
 	return nil
 }
 
+// NewOutlet creates the outlet of the name, or nil if it is unknown.
+// If the args do not match the schema, the outlet fails to open.
 func NewOutlet(name string, args ...string) report.Outlet {
 	if reg, ok := outletRegistry[name]; ok {
+		if err := reg.Args.Validate(args); err != nil {
+			return &failOutlet{failPlugin{err: fmt.Errorf("outlet %s, %s", name, err)}}
+		}
 		return reg.Factory(args...)
 	}
 	return nil
 }
 
+// NewProcessor creates the processor of the name, or nil if it is unknown.
+// If the args do not match the schema, the processor fails to open.
+func NewProcessor(name string, args ...string) report.Processor {
+	if reg, ok := processorRegistry[name]; ok {
+		if err := reg.Args.Validate(args); err != nil {
+			return &failProcessor{failPlugin{err: fmt.Errorf("processor %s, %s", name, err)}}
+		}
+		return reg.Factory(args...)
+	}
+	return nil
+}
+
+// failPlugin is the plugin of the invalid args, that fails to open,
+// so that the factories are not called with the args they do not expect.
+type failPlugin struct {
+	err error
+}
+
+func (fp *failPlugin) Open() error  { return fp.err }
+func (fp *failPlugin) Close() error { return nil }
+func (fp *failPlugin) Handle(context.Context) ([]*report.Record, error) {
+	return nil, fp.err
+}
+
+type failOutlet struct{ failPlugin }
+
+func (fo *failOutlet) Handle([]*report.Report) error { return fo.err }
+
+type failProcessor struct{ failPlugin }
+
+func (fp *failProcessor) Handle(r []*report.Report) ([]*report.Report, error) { return r, fp.err }
+
 func GetInletNames() []string {
 	return inletNames
 }
@@ -86,6 +162,10 @@ func GetOutletNames() []string {
 	return outletNames
 }
 
+func GetProcessorNames() []string {
+	return processorNames
+}
+
 func GetInletRegistry(name string) *InletReg {
 	if reg, ok := inletRegistry[name]; ok {
 		return reg
@@ -100,12 +180,50 @@ func GetOutletRegistry(name string) *OutletReg {
 	return nil
 }
 
+func GetProcessorRegistry(name string) *ProcessorReg {
+	if reg, ok := processorRegistry[name]; ok {
+		return reg
+	}
+	return nil
+}
+
+// ErrInletBusy is the error of a collection while the previous one is still running.
+var ErrInletBusy = errors.New("previous collection is still running")
+
+// InletFuncWrap is the inlet of the function, the function must return
+// when the ctx is done. Otherwise it keeps running after the timeout,
+// and the next collections fail until it returns.
 type InletFuncWrap struct {
-	fn func() ([]*report.Record, error)
+	fn func(context.Context) ([]*report.Record, error)
+	// busy is held while the function is running
+	busy sync.Mutex
 }
 
-func (in *InletFuncWrap) Handle() ([]*report.Record, error) {
-	return in.fn()
+type inletResult struct {
+	recs []*report.Record
+	err  error
+}
+
+// Handle calls the function, and gives it up when the ctx is done,
+// in case the function blocks regardless of the ctx.
+// Only one call of the function runs at a time, a call that has been given up
+// makes the following ones fail until it returns.
+func (in *InletFuncWrap) Handle(ctx context.Context) ([]*report.Record, error) {
+	if !in.busy.TryLock() {
+		return nil, ErrInletBusy
+	}
+	ch := make(chan inletResult, 1)
+	go func() {
+		defer in.busy.Unlock()
+		recs, err := in.fn(ctx)
+		ch <- inletResult{recs: recs, err: err}
+	}()
+	select {
+	case ret := <-ch:
+		return ret.recs, ret.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
 func (in *InletFuncWrap) Open() error {
@@ -116,55 +234,214 @@ func (in *InletFuncWrap) Close() error {
 	return nil
 }
 
-func NewInletFunc(fn func() ([]*report.Record, error)) func(...string) report.Inlet {
+func NewInletFunc(fn func(context.Context) ([]*report.Record, error)) func(...string) report.Inlet {
 	return func(args ...string) report.Inlet {
 		return &InletFuncWrap{fn: fn}
 	}
 }
 
-func NewInletFuncArgs(fn func([]string) func() ([]*report.Record, error)) func(...string) report.Inlet {
+func NewInletFuncArgs(fn func([]string) func(context.Context) ([]*report.Record, error)) func(...string) report.Inlet {
 	return func(args ...string) report.Inlet {
 		return &InletFuncWrap{fn: fn(args)}
 	}
 }
 
+type ProcessorFuncWrap struct {
+	fn    func([]*report.Report) ([]*report.Report, error)
+	flush func() []*report.Report
+}
+
+func (p *ProcessorFuncWrap) Handle(r []*report.Report) ([]*report.Report, error) {
+	return p.fn(r)
+}
+
+// Flush implements report.Flusher.
+func (p *ProcessorFuncWrap) Flush() []*report.Report {
+	if p.flush == nil {
+		return nil
+	}
+	return p.flush()
+}
+
+func (p *ProcessorFuncWrap) Open() error {
+	return nil
+}
+
+func (p *ProcessorFuncWrap) Close() error {
+	return nil
+}
+
+func NewProcessorFuncArgs(fn func([]string) func([]*report.Report) ([]*report.Report, error)) func(...string) report.Processor {
+	return func(args ...string) report.Processor {
+		return &ProcessorFuncWrap{fn: fn(args)}
+	}
+}
+
+// NewProcessorFlushFuncArgs is NewProcessorFuncArgs of the processor that holds the records,
+// the flush returns the pending reports when the processor is stopped or replaced.
+func NewProcessorFlushFuncArgs(fn func([]string) (func([]*report.Report) ([]*report.Report, error), func() []*report.Report)) func(...string) report.Processor {
+	return func(args ...string) report.Processor {
+		handle, flush := fn(args)
+		return &ProcessorFuncWrap{fn: handle, flush: flush}
+	}
+}
+
+// format args of the outlets, see internal.outletFormat
+var outletFormatArgs = []ArgSpec{
+	{Name: "name_template", Type: ArgString, Keyword: true, Default: "{measurement}.{tags}.{field}",
+		Desc: "Template to flatten the tags into the name"},
+	{Name: "tags", Type: ArgString, Keyword: true, Enum: []string{"json"},
+		Desc: "Append the tags as a JSON column"},
+}
+
 func init() {
 	// inputs
-	RegisterInletWith("in-cpu", NewInletFunc(internal.CpuInput), false,
-		"--in-cpu                Report CPU usage")
+	RegisterInletWith("in-cpu", NewInletFuncArgs(internal.CpuInput), "",
+		"--in-cpu [metrics]      Report CPU usage, comma(,) separated (default total)\n"+
+			"                        Available: total,percpu,times",
+		ArgSpec{Name: "metrics", Type: ArgList, Default: "total", Enum: []string{"total", "percpu", "times"},
+			Desc: "'total' of all CPUs, 'percpu' of each CPU, 'times' the share of user, system, iowait, steal, etc."})
 	RegisterInletWith("in-load", NewInletFunc(internal.LoadInput), false,
 		"--in-load               Report load average")
-	RegisterInletWith("in-mem", NewInletFunc(internal.MemInput), false,
-		"--in-mem                Report memory usage")
+	RegisterInletWith("in-mem", NewInletFuncArgs(internal.MemInput), false,
+		"--in-mem                Report memory and swap usage")
 	RegisterInletWith("in-disk", NewInletFuncArgs(internal.DiskInput), "",
 		"--in-disk <path>        Report disk usage by mount point, comma(,) separated,\n"+
-			"                        (e.g. /,/mnt/disk/). Set 'all' for all mount points.")
+			"                        wildcard(*) is allowed (e.g. /,/mnt/*). Set 'all' for all mount points.\n"+
+			"                        exclude=<path>, fstypes=<types> and exclude_fstypes=<types> to filter\n"+
+			"                        (e.g. exclude_fstypes=tmpfs,devtmpfs,overlay,squashfs)",
+		ArgSpec{Name: "mountpoints", Type: ArgList, Required: true, Default: "all",
+			Desc: "Mount points, wildcard(*) is allowed, 'all' for all mount points"},
+		ArgSpec{Name: "exclude", Type: ArgList, Keyword: true,
+			Desc: "Mount points to exclude, wildcard(*) is allowed, e.g. /snap/*"},
+		ArgSpec{Name: "fstypes", Type: ArgList, Keyword: true,
+			Desc: "File system types to include, e.g. ext4,xfs"},
+		ArgSpec{Name: "exclude_fstypes", Type: ArgList, Keyword: true,
+			Desc: "File system types to exclude, e.g. tmpfs,devtmpfs,overlay,squashfs"})
 	RegisterInletWith("in-diskio", NewInletFuncArgs(internal.DiskioInput), "",
 		"--in-diskio <dev>       Report disk I/O by dev name, comma(,) separated,\n"+
-			"                        wildcard(*) is allowed (e.g. sda,sdb,sd*)")
+			"                        wildcard(*) is allowed (e.g. sda,sdb,sd*),\n"+
+			"                        exclude=<dev> to exclude the partitions and the virtual devices\n"+
+			"                        (e.g. exclude=loop*,dm-*,ram*)",
+		ArgSpec{Name: "devices", Type: ArgList, Required: true, Desc: "Device names, wildcard(*) is allowed"},
+		ArgSpec{Name: "exclude", Type: ArgList, Keyword: true,
+			Desc: "Device names to exclude, wildcard(*) is allowed, e.g. loop*,dm-*,ram*"})
 	RegisterInletWith("in-net", NewInletFuncArgs(internal.NetInput), "",
-		"--in-net <iface>        Report network I/O, comma(,) separated,\n"+
-			"                        wildcard(*) is allowed (e.g. eth0,en0,enp*)")
+		"--in-net <iface>        Report network I/O, errors, link state and utilization,\n"+
+			"                        comma(,) separated, wildcard(*) is allowed (e.g. eth0,en0,enp*)",
+		ArgSpec{Name: "interfaces", Type: ArgList, Required: true, Desc: "Interface names, wildcard(*) is allowed"})
 	RegisterInletWith("in-proto", NewInletFuncArgs(internal.ProtoInput), "",
 		"--in-proto <proto>      Report network I/O by protocol, comma(,) separated\n"+
-			"                        Available: ip,icmp,icmpmsg,tcp,udp,udplite")
+			"                        Available: ip,icmp,icmpmsg,tcp,udp,udplite",
+		ArgSpec{Name: "protocols", Type: ArgList, Required: true,
+			Enum: []string{"ip", "icmp", "icmpmsg", "tcp", "udp", "udplite"}, Desc: "Protocols"})
 	RegisterInletWith("in-sensor", NewInletFunc(internal.SensorInput), false,
 		"--in-sensor             Report sensors (temperature, fan speed, etc.)")
 	RegisterInletWith("in-host", NewInletFunc(internal.HostInput), false,
 		"--in-host               Report host information")
+	RegisterInletWith("in-procstat", NewInletFuncArgs(internal.ProcstatInput), "",
+		"--in-procstat <selectors>\n"+
+			"                        Report the processes that match all of the selectors,\n"+
+			"                        name=<glob>,cmdline=<regexp>,pidfile=<path>,user=<user>\n"+
+			"                        (e.g. name=machbase-neo)",
+		ArgSpec{Name: "name", Type: ArgList, Keyword: true, Desc: "Glob patterns of the process name"},
+		ArgSpec{Name: "cmdline", Type: ArgRegexp, Keyword: true, Desc: "Regular expression of the command line"},
+		ArgSpec{Name: "pidfile", Type: ArgString, Keyword: true, Desc: "File that has the pid of the process"},
+		ArgSpec{Name: "user", Type: ArgString, Keyword: true, Desc: "User of the process"},
+		ArgSpec{Name: "pid_tag", Type: ArgBool, Keyword: true, Default: "false",
+			Desc: "Report each process tagged with the pid, instead of the sum of the processes of the same name"},
+		ArgSpec{Name: "label", Type: ArgString, Keyword: true,
+			Desc: "Selector tag of procstat.count, default the name, the pidfile or the user"})
+	RegisterInletWith("in-top", NewInletFuncArgs(internal.TopInput), "",
+		"--in-top [n] [max]      Report the top n processes by CPU and by memory (default 5),\n"+
+			"                        up to max process names (default 50), the others are 'other',\n"+
+			"                        expire=<duration> frees the places of the names not reported since",
+		ArgSpec{Name: "n", Type: ArgInt, Default: "5", Desc: "Number of the top processes"},
+		ArgSpec{Name: "max_series", Type: ArgInt, Default: "50",
+			Desc: "Cap of the process names, the processes over the cap are reported as 'other'"},
+		ArgSpec{Name: "expire", Type: ArgDuration, Keyword: true,
+			Desc: "Time after which a process name that has not been reported gives its place to another, default never"})
+	RegisterInletWith("in-cgroup", NewInletFuncArgs(internal.CgroupInput), "",
+		"--in-cgroup <glob>      Report the resources of the cgroups (v2), comma(,) separated,\n"+
+			"                        relative to /sys/fs/cgroup (e.g. system.slice/machbase-neo.service)",
+		ArgSpec{Name: "cgroups", Type: ArgList, Required: true,
+			Desc: "Glob patterns of the cgroups relative to the root"},
+		ArgSpec{Name: "root", Type: ArgString, Keyword: true, Default: "/sys/fs/cgroup",
+			Desc: "Mount point of the cgroup2 file system"})
+	RegisterInletWith("in-pressure", NewInletFuncArgs(internal.PressureInput), "",
+		"--in-pressure [res]     Report the pressure stall information of Linux, comma(,) separated\n"+
+			"                        Available: cpu,memory,io (default all)",
+		ArgSpec{Name: "resources", Type: ArgList, Default: "cpu,memory,io", Enum: []string{"cpu", "memory", "io"},
+			Desc: "Resources"},
+		ArgSpec{Name: "cgroups", Type: ArgList, Keyword: true,
+			Desc: "Glob patterns of the cgroups relative to the root, to report their pressure too"},
+		ArgSpec{Name: "root", Type: ArgString, Keyword: true, Default: "/sys/fs/cgroup",
+			Desc: "Mount point of the cgroup2 file system"})
+	RegisterInletWith("in-kernel", NewInletFuncArgs(internal.KernelInput), false,
+		"--in-kernel             Report kernel activity of Linux, context switches, interrupts,\n"+
+			"                        forks, page faults, paging, swapping and OOM kills")
 	RegisterInletWith("in-neo-statz", NewInletFuncArgs(internal.NeoStatzInput), false,
-		"--in-neo-statz          Report machbase-neo statz")
+		"--in-neo-statz          Report machbase-neo statz",
+		ArgSpec{Name: "addr", Type: ArgString, Required: true, Desc: "HTTP address of machbase-neo"})
 	RegisterInletWith("in-neo-table-rows-counter", NewInletFuncArgs(internal.NeoTableRowsCounterInput), false,
-		"--in-neo-table-rows-counter  Report machbase-neo table counter")
+		"--in-neo-table-rows-counter  Report machbase-neo table counter",
+		ArgSpec{Name: "addr", Type: ArgString, Required: true, Desc: "HTTP address of machbase-neo"},
+		ArgSpec{Name: "tables", Type: ArgString, Variadic: true, Desc: "Tables to count the rows"})
+	// processors
+	RegisterProcessorWith("proc-include", NewProcessorFuncArgs(internal.IncludeProcessor), "",
+		"--proc-include <glob>   Pass only the records whose name matches, comma(,) separated\n"+
+			"                        (e.g. neo_cpu.*,neo_mem.*)",
+		ArgSpec{Name: "patterns", Type: ArgList, Required: true, Desc: "Glob patterns of the names"})
+	RegisterProcessorWith("proc-exclude", NewProcessorFuncArgs(internal.ExcludeProcessor), "",
+		"--proc-exclude <glob>   Drop the records whose name matches, comma(,) separated",
+		ArgSpec{Name: "patterns", Type: ArgList, Required: true, Desc: "Glob patterns of the names"})
+	RegisterProcessorWith("proc-rename", NewProcessorFuncArgs(internal.RenameProcessor), "",
+		"--proc-rename <regexp> <replacement>\n"+
+			"                        Rename the records by the regular expression",
+		ArgSpec{Name: "regexp", Type: ArgRegexp, Required: true, Desc: "Regular expression of the names"},
+		ArgSpec{Name: "replacement", Type: ArgString, Required: true, Desc: "Replacement, $1 for the submatch"})
+	RegisterProcessorWith("proc-scale", NewProcessorFuncArgs(internal.ScaleProcessor), "",
+		"--proc-scale <glob> <factor>\n"+
+			"                        Multiply the values of the matched records by the factor",
+		ArgSpec{Name: "patterns", Type: ArgList, Required: true, Desc: "Glob patterns of the names"},
+		ArgSpec{Name: "factor", Type: ArgFloat, Required: true, Desc: "Factor to multiply"})
+	RegisterProcessorWith("proc-rate", NewProcessorFuncArgs(internal.RateProcessor), "",
+		"--proc-rate <glob> [add|replace]\n"+
+			"                        Derive per-second rates of the matched counters,\n"+
+			"                        'add' emits <name>_rate next to the counter (default),\n"+
+			"                        'replace' emits the rate instead of the counter,\n"+
+			"                        counter_bits=32|64 takes a counter going backward as a wraparound",
+		ArgSpec{Name: "patterns", Type: ArgList, Required: true, Desc: "Glob patterns of the counters"},
+		ArgSpec{Name: "mode", Type: ArgString, Default: "add", Enum: []string{"add", "replace"},
+			Desc: "'add' emits <name>_rate next to the counter, 'replace' emits the rate instead"},
+		ArgSpec{Name: "suffix", Type: ArgString, Keyword: true, Default: "_rate", Desc: "Suffix of the rate records"},
+		ArgSpec{Name: "counter_bits", Type: ArgInt, Keyword: true, Enum: []string{"32", "64"},
+			Desc: "Width of the counters, a counter going backward is a wraparound instead of a reset"})
+	RegisterProcessorWith("proc-aggregate", NewProcessorFlushFuncArgs(internal.AggregateProcessor), "",
+		"--proc-aggregate <glob> <window> [stats]\n"+
+			"                        Summarize the matched records over the wall-clock aligned window,\n"+
+			"                        stats: min,max,avg,last,count,p95 (default min,max,avg,last,count)",
+		ArgSpec{Name: "patterns", Type: ArgList, Required: true, Desc: "Glob patterns of the names"},
+		ArgSpec{Name: "window", Type: ArgDuration, Required: true, Desc: "Window, e.g. 1m"},
+		ArgSpec{Name: "stats", Type: ArgList, Default: "min,max,avg,last,count",
+			Enum: []string{"min", "max", "avg", "last", "count", "p95"}, Desc: "Statistics"})
+	RegisterProcessorWith("proc-drop-nan", NewProcessorFuncArgs(internal.DropNaNProcessor), false,
+		"--proc-drop-nan         Drop the records of NaN or Inf value")
 	// outputs
 	RegisterOutletWith("out-file", internal.NewFileOutlet, "",
-		"--out-file <path>       Report output to the file")
+		"--out-file <path>       Report output to the file",
+		append([]ArgSpec{{Name: "path", Type: ArgString, Required: true, Desc: "Path of the file, '-' for stdout"}},
+			outletFormatArgs...)...)
 	RegisterOutletWith("out-http", internal.NewHttpOutlet, "",
 		"--out-http <addr>       Report output to the HTTP server\n"+
-			"                        e.g. http://localhost:5654/db/write/EXAMPLE?timeformat=s&method=append")
+			"                        e.g. http://localhost:5654/db/write/EXAMPLE?timeformat=s&method=append",
+		append([]ArgSpec{{Name: "addr", Type: ArgString, Required: true, Desc: "URL to write"}},
+			outletFormatArgs...)...)
 	RegisterOutletWith("out-mqtt", internal.NewMqttOutlet, "",
 		"--out-mqtt <addr/topic> Report output to the MQTT server.\n"+
-			"                        e.g. tcp://localhost:5653/db/append/EXAMPLE:csv")
+			"                        e.g. tcp://localhost:5653/db/append/EXAMPLE:csv",
+		append([]ArgSpec{{Name: "addr", Type: ArgString, Required: true, Desc: "Address and topic to publish"}},
+			outletFormatArgs...)...)
 }
 
 func PrintUsage() {
@@ -176,6 +453,14 @@ func PrintUsage() {
 			fmt.Printf("    %s\n", l)
 		}
 	}
+	fmt.Println("\nProcessor Options:")
+	for _, n := range GetProcessorNames() {
+		reg := GetProcessorRegistry(n)
+		lines := strings.Split(reg.ArgDesc, "\n")
+		for _, l := range lines {
+			fmt.Printf("    %s\n", l)
+		}
+	}
 	fmt.Println("\nOutput Options:")
 	for _, n := range GetOutletNames() {
 		reg := GetOutletRegistry(n)
@@ -184,4 +469,8 @@ func PrintUsage() {
 			fmt.Printf("    %s\n", l)
 		}
 	}
+	fmt.Println("\nOutput Format Options: (append to the output address)")
+	fmt.Println("    name_template=<tmpl>    Template to flatten the tags into the name,")
+	fmt.Println("                            default \"{measurement}.{tags}.{field}\"")
+	fmt.Println("    tags=json               Append the tags as a JSON column")
 }
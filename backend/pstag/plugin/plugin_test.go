@@ -0,0 +1,45 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInletFuncWrapBusy(t *testing.T) {
+	release := make(chan struct{})
+	inlet := &InletFuncWrap{fn: func(ctx context.Context) ([]*report.Record, error) {
+		<-release
+		return []*report.Record{{Name: "const", Value: 1}}, nil
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := inlet.Handle(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the function that ignores the ctx is still running
+	_, err = inlet.Handle(context.Background())
+	require.ErrorIs(t, err, ErrInletBusy)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		recs, err := inlet.Handle(context.Background())
+		return err == nil && len(recs) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestNewInletInvalidArgs(t *testing.T) {
+	// in-disk requires the mount points
+	inlet := NewInlet("in-disk")
+	require.NotNil(t, inlet)
+	require.ErrorContains(t, inlet.Open(), "missing")
+
+	require.Nil(t, NewInlet("in-unknown"))
+	require.Error(t, NewProcessor("proc-aggregate", "cpu.*").Open())
+	require.NoError(t, NewProcessor("proc-aggregate", "cpu.*", "1m").Open())
+}
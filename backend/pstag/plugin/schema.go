@@ -0,0 +1,174 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ArgType string
+
+const (
+	ArgString   ArgType = "string"
+	ArgList     ArgType = "list" // comma(,) separated values
+	ArgInt      ArgType = "int"
+	ArgFloat    ArgType = "float"
+	ArgBool     ArgType = "bool"
+	ArgDuration ArgType = "duration"
+	ArgRegexp   ArgType = "regexp"
+)
+
+// ArgSpec describes an argument of a plugin.
+// The args are positional in the order of the specs,
+// except the Keyword args that are given as "<name>=<value>" after them.
+type ArgSpec struct {
+	Name     string   `json:"name"`
+	Type     ArgType  `json:"type"`
+	Required bool     `json:"required,omitempty"`
+	Default  string   `json:"default,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+	Desc     string   `json:"desc,omitempty"`
+	// Variadic is the last positional arg that takes the rest of the args.
+	Variadic bool `json:"variadic,omitempty"`
+	Keyword  bool `json:"keyword,omitempty"`
+}
+
+type ArgSpecs []ArgSpec
+
+// Validate checks the args against the specs.
+func (specs ArgSpecs) Validate(args []string) error {
+	positional := []string{}
+	for _, arg := range args {
+		if k, v, ok := strings.Cut(arg, "="); ok {
+			if idx := slices.IndexFunc(specs, func(s ArgSpec) bool { return s.Keyword && s.Name == k }); idx >= 0 {
+				if err := specs[idx].check(v); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		positional = append(positional, arg)
+	}
+	i := 0
+	for _, spec := range specs {
+		if spec.Keyword {
+			continue
+		}
+		if i >= len(positional) {
+			if spec.Required {
+				return fmt.Errorf("missing %s", spec.Name)
+			}
+			continue
+		}
+		values := positional[i : i+1]
+		if spec.Variadic {
+			values = positional[i:]
+		}
+		for _, v := range values {
+			if err := spec.check(v); err != nil {
+				return err
+			}
+		}
+		i += len(values)
+	}
+	if i < len(positional) {
+		return fmt.Errorf("too many args, %q", strings.Join(positional[i:], " "))
+	}
+	return nil
+}
+
+func (spec ArgSpec) check(val string) error {
+	values := []string{strings.TrimSpace(val)}
+	if spec.Type == ArgList {
+		values = strings.Split(val, ",")
+	}
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" && !spec.Required {
+			continue
+		}
+		if len(spec.Enum) > 0 && !slices.Contains(spec.Enum, v) {
+			return fmt.Errorf("%s %q is not one of %s", spec.Name, v, strings.Join(spec.Enum, ","))
+		}
+		var err error
+		switch spec.Type {
+		case ArgInt:
+			_, err = strconv.Atoi(v)
+		case ArgFloat:
+			_, err = strconv.ParseFloat(v, 64)
+		case ArgBool:
+			_, err = strconv.ParseBool(v)
+		case ArgDuration:
+			_, err = time.ParseDuration(v)
+		case ArgRegexp:
+			_, err = regexp.Compile(v)
+		}
+		if err != nil {
+			return fmt.Errorf("%s %q is wrong value", spec.Name, v)
+		}
+	}
+	return nil
+}
+
+// PluginInfo is a registered plugin in the catalog.
+type PluginInfo struct {
+	Name string   `json:"name"`
+	Desc string   `json:"desc"`
+	Args ArgSpecs `json:"args"`
+}
+
+type Catalog struct {
+	Inlets     []PluginInfo `json:"inlets"`
+	Processors []PluginInfo `json:"processors"`
+	Outlets    []PluginInfo `json:"outlets"`
+}
+
+// GetCatalog lists the registered plugins in the order of registration.
+func GetCatalog() Catalog {
+	regLock.Lock()
+	defer regLock.Unlock()
+	ret := Catalog{Inlets: []PluginInfo{}, Processors: []PluginInfo{}, Outlets: []PluginInfo{}}
+	for _, n := range inletNames {
+		reg := inletRegistry[n]
+		ret.Inlets = append(ret.Inlets, newPluginInfo(reg.Name, reg.ArgDesc, reg.Args))
+	}
+	for _, n := range processorNames {
+		reg := processorRegistry[n]
+		ret.Processors = append(ret.Processors, newPluginInfo(reg.Name, reg.ArgDesc, reg.Args))
+	}
+	for _, n := range outletNames {
+		reg := outletRegistry[n]
+		ret.Outlets = append(ret.Outlets, newPluginInfo(reg.Name, reg.ArgDesc, reg.Args))
+	}
+	return ret
+}
+
+func newPluginInfo(name string, argDesc string, args ArgSpecs) PluginInfo {
+	if args == nil {
+		args = ArgSpecs{}
+	}
+	return PluginInfo{Name: name, Desc: describe(argDesc), Args: args}
+}
+
+var usageColumn = regexp.MustCompile(`\s{2,}`)
+
+// describe takes the description out of the usage of the command line,
+// e.g. "--in-cpu                Report CPU usage" to "Report CPU usage".
+func describe(argDesc string) string {
+	ret := []string{}
+	for _, line := range strings.Split(argDesc, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "--") {
+			cols := usageColumn.Split(line, 2)
+			if len(cols) < 2 {
+				continue
+			}
+			line = cols[1]
+		}
+		ret = append(ret, line)
+	}
+	return strings.Join(ret, " ")
+}
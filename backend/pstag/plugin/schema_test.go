@@ -0,0 +1,36 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArgSpecsValidate(t *testing.T) {
+	specs := GetProcessorRegistry("proc-aggregate").Args
+	require.NoError(t, specs.Validate([]string{"cpu.*", "1m"}))
+	require.NoError(t, specs.Validate([]string{"cpu.*", "1m", "min,p95"}))
+	require.ErrorContains(t, specs.Validate([]string{"cpu.*"}), "missing window")
+	require.ErrorContains(t, specs.Validate([]string{"cpu.*", "1x"}), "window")
+	require.ErrorContains(t, specs.Validate([]string{"cpu.*", "1m", "min,p50"}), "p50")
+	require.ErrorContains(t, specs.Validate([]string{"cpu.*", "1m", "min", "max"}), "too many args")
+
+	specs = GetOutletRegistry("out-mqtt").Args
+	require.NoError(t, specs.Validate([]string{"tcp://127.0.0.1:5653/db/append/EXAMPLE:csv", "tags=json"}))
+	require.Error(t, specs.Validate([]string{"tcp://127.0.0.1:5653/db/append/EXAMPLE:csv", "tags=xml"}))
+
+	specs = GetInletRegistry("in-neo-table-rows-counter").Args
+	require.NoError(t, specs.Validate([]string{"http://127.0.0.1:5654", "example", "tag"}))
+}
+
+func TestCatalog(t *testing.T) {
+	catalog := GetCatalog()
+	require.Len(t, catalog.Inlets, len(GetInletNames()))
+	require.Len(t, catalog.Processors, len(GetProcessorNames()))
+	require.Len(t, catalog.Outlets, len(GetOutletNames()))
+	require.Equal(t, "in-cpu", catalog.Inlets[0].Name)
+	require.Equal(t, "Report CPU usage, comma(,) separated (default total) Available: total,percpu,times", catalog.Inlets[0].Desc)
+	require.Equal(t, "in-load", catalog.Inlets[1].Name)
+	require.Equal(t, "Report load average", catalog.Inlets[1].Desc)
+	require.Equal(t, "Rename the records by the regular expression", describe(GetProcessorRegistry("proc-rename").ArgDesc))
+}
@@ -1,7 +1,9 @@
 package pstag
 
 import (
+	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
@@ -17,6 +19,11 @@ func New(opts ...Option) *PsTag {
 		ret.reportCh = make(chan *report.Report, 100)
 		ret.shouldCloseReportCh = true
 	}
+	if ret.selfStats {
+		in := NewInputFunc(ret.reportCh, &selfInlet{pt: ret})
+		WithInputName(SelfStatsInput)(in)
+		ret.inputs = append(ret.inputs, in)
+	}
 	return ret
 }
 
@@ -34,6 +41,13 @@ func WithTagPrefix(prefix string) Option {
 	}
 }
 
+// WithTags adds the tags to every record, unless the record already has the tag.
+func WithTags(tags []report.Tag) Option {
+	return func(pt *PsTag) {
+		pt.tags = tags
+	}
+}
+
 func WithReportCh(ch chan *report.Report) Option {
 	return func(pt *PsTag) {
 		pt.reportCh = ch
@@ -43,39 +57,249 @@ func WithReportCh(ch chan *report.Report) Option {
 type PsTag struct {
 	interval            time.Duration
 	tagPrefix           string
+	tags                []report.Tag
 	inputs              []*InputHandler
+	processors          []report.Processor
 	outputs             []*OutputHandler
 	reportCh            chan *report.Report
 	shouldCloseReportCh bool
+	selfStats           bool
 
+	// lock guards the tags, inputs, processors and outputs,
+	// they can be changed while running.
+	lock      sync.RWMutex
 	closeCh   chan bool
 	closeWg   sync.WaitGroup
 	isRunning bool
 }
 
-func (pt *PsTag) AddInput(inlet report.Inlet) {
-	pt.inputs = append(pt.inputs, NewInputFunc(pt.reportCh, inlet))
+// AddInput adds the inlet, it is started immediately if the PsTag is running.
+func (pt *PsTag) AddInput(inlet report.Inlet, opts ...InputOption) error {
+	in := NewInputFunc(pt.reportCh, inlet)
+	for _, opt := range opts {
+		opt(in)
+	}
+	pt.lock.RLock()
+	running, interval := pt.isRunning, pt.interval
+	pt.lock.RUnlock()
+
+	// start it out of the lock, the input sends the first report immediately
+	if running {
+		if err := in.Start(interval); err != nil {
+			return err
+		}
+	}
+	pt.lock.Lock()
+	pt.inputs = append(pt.inputs, in)
+	pt.lock.Unlock()
+	return nil
+}
+
+// RemoveInput stops and removes the input of the name.
+func (pt *PsTag) RemoveInput(name string) error {
+	pt.lock.Lock()
+	idx := slices.IndexFunc(pt.inputs, func(in *InputHandler) bool { return in.name == name })
+	if idx < 0 {
+		pt.lock.Unlock()
+		return fmt.Errorf("input %q not found", name)
+	}
+	in := pt.inputs[idx]
+	pt.inputs = slices.Delete(pt.inputs, idx, idx+1)
+	running := pt.isRunning
+	pt.lock.Unlock()
+
+	// stop it out of the lock, the input may be sending a report
+	if running {
+		in.Stop()
+	}
+	return nil
+}
+
+// ReplaceInput replaces the input of the name with the inlet.
+func (pt *PsTag) ReplaceInput(name string, inlet report.Inlet, opts ...InputOption) error {
+	if err := pt.RemoveInput(name); err != nil {
+		return err
+	}
+	return pt.AddInput(inlet, append(opts, WithInputName(name))...)
+}
+
+// AddProcessor appends the processor to the chain of processors,
+// the processors are applied in the order they were added.
+func (pt *PsTag) AddProcessor(proc report.Processor) error {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+	if pt.isRunning {
+		if err := proc.Open(); err != nil {
+			return err
+		}
+	}
+	pt.processors = append(pt.processors, proc)
+	return nil
+}
+
+// SetProcessors replaces the chain of processors.
+// The processors that are in both of the chains are kept as they are,
+// the pending reports of the removed processors are flushed to the outputs.
+func (pt *PsTag) SetProcessors(procs []report.Processor) error {
+	pt.lock.Lock()
+	if !pt.isRunning {
+		pt.processors = procs
+		pt.lock.Unlock()
+		return nil
+	}
+	opened := []report.Processor{}
+	for _, proc := range procs {
+		if slices.Contains(pt.processors, proc) {
+			continue
+		}
+		if err := proc.Open(); err != nil {
+			for _, p := range opened {
+				p.Close()
+			}
+			pt.lock.Unlock()
+			return err
+		}
+		opened = append(opened, proc)
+	}
+	removed := func(proc report.Processor) bool { return !slices.Contains(procs, proc) }
+	pending := flush(pt.processors, removed)
+	for _, proc := range pt.processors {
+		if !removed(proc) {
+			continue
+		}
+		if err := proc.Close(); err != nil {
+			slog.Error("failed to close processor", "error", err.Error())
+		}
+	}
+	pt.processors = procs
+	outputs := slices.Clone(pt.outputs)
+	pt.lock.Unlock()
+
+	// enqueue out of the lock, an output may block when its queue is full
+	enqueue(outputs, pending)
+	return nil
+}
+
+// SetTagPrefix replaces the prefix of the names of the records.
+func (pt *PsTag) SetTagPrefix(prefix string) {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+	pt.tagPrefix = prefix
 }
 
-func (pt *PsTag) AddOutput(outlet report.Outlet) {
-	pt.outputs = append(pt.outputs, NewOutputHandler(outlet, pt.interval))
+// SetTags replaces the tags that are added to every record.
+func (pt *PsTag) SetTags(tags []report.Tag) {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+	pt.tags = tags
+}
+
+// AddOutput adds the outlet, it is started immediately if the PsTag is running.
+func (pt *PsTag) AddOutput(outlet report.Outlet, opts ...OutputOption) error {
+	pt.lock.RLock()
+	running, interval := pt.isRunning, pt.interval
+	pt.lock.RUnlock()
+	out := NewOutputHandler(outlet, interval, opts...)
+
+	// start it out of the lock, the outlet may take a while to connect
+	if running {
+		if err := out.Start(); err != nil {
+			return err
+		}
+	}
+	pt.lock.Lock()
+	pt.outputs = append(pt.outputs, out)
+	pt.lock.Unlock()
+	return nil
+}
+
+// RemoveOutput stops and removes the output of the name.
+func (pt *PsTag) RemoveOutput(name string) error {
+	pt.lock.Lock()
+	idx := slices.IndexFunc(pt.outputs, func(out *OutputHandler) bool { return out.name == name })
+	if idx < 0 {
+		pt.lock.Unlock()
+		return fmt.Errorf("output %q not found", name)
+	}
+	out := pt.outputs[idx]
+	pt.outputs = slices.Delete(pt.outputs, idx, idx+1)
+	running := pt.isRunning
+	pt.lock.Unlock()
+
+	if running {
+		out.Stop()
+	}
+	return nil
 }
 
-func (pt *PsTag) Run() {
+// ReplaceOutput replaces the output of the name with the outlet.
+func (pt *PsTag) ReplaceOutput(name string, outlet report.Outlet, opts ...OutputOption) error {
+	if err := pt.RemoveOutput(name); err != nil {
+		return err
+	}
+	return pt.AddOutput(outlet, append(opts, WithOutputName(name))...)
+}
+
+func (pt *PsTag) OutputHealth() []OutputHealth {
+	pt.lock.RLock()
+	defer pt.lock.RUnlock()
+	ret := make([]OutputHealth, 0, len(pt.outputs))
+	for _, out := range pt.outputs {
+		ret = append(ret, out.Health())
+	}
+	return ret
+}
+
+// Run starts the outputs, the processors and the inputs.
+// If any of them fails to start, the started ones are stopped
+// and the PsTag is not running.
+func (pt *PsTag) Run() error {
+	if err := pt.start(); err != nil {
+		return err
+	}
+	// the inputs are started out of the lock, as they report immediately
+	// and the self stats input reads the PsTag.
+	pt.lock.RLock()
+	inputs, interval := slices.Clone(pt.inputs), pt.interval
+	pt.lock.RUnlock()
+	for _, in := range inputs {
+		if err := in.Start(interval); err != nil {
+			pt.Stop()
+			return fmt.Errorf("input %q, %w", in.name, err)
+		}
+	}
+	return nil
+}
+
+// start starts the outputs, the processors and the loop of the reports.
+func (pt *PsTag) start() error {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+
 	if pt.interval < 1*time.Second {
 		pt.interval = 1 * time.Second
 	}
 
-	slog.Info("set", "interval", pt.interval, "inputs", len(pt.inputs), "outputs", len(pt.outputs))
+	slog.Info("set", "interval", pt.interval, "inputs", len(pt.inputs), "processors", len(pt.processors), "outputs", len(pt.outputs))
 
-	for _, out := range pt.outputs {
+	for i, out := range pt.outputs {
 		if err := out.Start(); err != nil {
-			return
+			for _, o := range pt.outputs[:i] {
+				o.Stop()
+			}
+			return fmt.Errorf("output %q, %w", out.name, err)
 		}
 	}
-	for _, in := range pt.inputs {
-		if err := in.Start(pt.interval, pt.tagPrefix); err != nil {
-			return
+	for i, proc := range pt.processors {
+		if err := proc.Open(); err != nil {
+			slog.Error("failed to open processor", "error", err.Error())
+			for _, p := range pt.processors[:i] {
+				p.Close()
+			}
+			for _, o := range pt.outputs {
+				o.Stop()
+			}
+			return err
 		}
 	}
 
@@ -83,33 +307,120 @@ func (pt *PsTag) Run() {
 	pt.closeWg.Add(1)
 	pt.isRunning = true
 	go func() {
-		defer func() {
-			pt.isRunning = false
-		}()
+		defer pt.closeWg.Done()
 		slog.Info("start")
 		for {
 			select {
 			case <-pt.closeCh:
-				pt.closeWg.Done()
 				return
 			case rpt := <-pt.reportCh:
-				for _, out := range pt.outputs {
-					out.Sink() <- rpt
-				}
+				pt.lock.RLock()
+				pt.applyTags(rpt)
+				rpts := pt.process([]*report.Report{rpt})
+				outputs := slices.Clone(pt.outputs)
+				pt.lock.RUnlock()
+				// enqueue out of the lock, an output may block when its queue is full,
+				// that should not block the changes of the inputs and the outputs.
+				enqueue(outputs, rpts)
 			}
 		}
 	}()
+	return nil
+}
+
+// applyTags prefixes the names and adds the tags to the records.
+func (pt *PsTag) applyTags(rpt *report.Report) {
+	for _, r := range rpt.Records {
+		if pt.tagPrefix != "" {
+			r.Name = pt.tagPrefix + r.Name
+		}
+		for _, t := range pt.tags {
+			if r.Tag(t.Key) == "" {
+				r.Tags = append(r.Tags, t)
+			}
+		}
+	}
+}
+
+// process passes the reports through the chain of processors.
+func (pt *PsTag) process(rpts []*report.Report) []*report.Report {
+	return processChain(pt.processors, rpts)
+}
+
+// processChain passes the reports through the processors.
+// If a processor fails, its input is passed to the next processor.
+func processChain(procs []report.Processor, rpts []*report.Report) []*report.Report {
+	for _, proc := range procs {
+		ret, err := proc.Handle(rpts)
+		if err != nil {
+			slog.Error("failed to process", "error", err.Error())
+			if ret == nil {
+				continue
+			}
+		}
+		rpts = ret
+		if len(rpts) == 0 {
+			break
+		}
+	}
+	return rpts
+}
+
+// flush returns the pending reports of the processors that match() returns true,
+// passed through the processors that follow them in the chain.
+// The caller holds the lock, or the loop of the reports has been stopped.
+func flush(procs []report.Processor, match func(report.Processor) bool) []*report.Report {
+	ret := []*report.Report{}
+	for i, proc := range procs {
+		f, ok := proc.(report.Flusher)
+		if !ok || !match(proc) {
+			continue
+		}
+		rpts := f.Flush()
+		if len(rpts) == 0 {
+			continue
+		}
+		ret = append(ret, processChain(procs[i+1:], rpts)...)
+	}
+	return ret
+}
+
+// enqueue sends the reports to the outputs.
+func enqueue(outputs []*OutputHandler, rpts []*report.Report) {
+	for _, r := range rpts {
+		for _, out := range outputs {
+			out.Enqueue(r)
+		}
+	}
 }
 
 func (pt *PsTag) Stop() {
-	for _, in := range pt.inputs {
+	pt.lock.Lock()
+	pt.isRunning = false
+	inputs, processors, outputs := pt.inputs, pt.processors, pt.outputs
+	pt.lock.Unlock()
+
+	for _, in := range inputs {
 		in.Stop()
 	}
-	for _, out := range pt.outputs {
+	// the loop is not started if an output or a processor failed to start
+	if pt.closeCh != nil {
+		pt.closeCh <- true
+		pt.closeWg.Wait()
+		pt.closeCh = nil
+	}
+	pt.lock.RLock()
+	pending := flush(processors, func(report.Processor) bool { return true })
+	pt.lock.RUnlock()
+	enqueue(outputs, pending)
+	for _, proc := range processors {
+		if err := proc.Close(); err != nil {
+			slog.Error("failed to close processor", "error", err.Error())
+		}
+	}
+	for _, out := range outputs {
 		out.Stop()
 	}
-	pt.closeCh <- true
-	pt.closeWg.Wait()
 
 	if pt.shouldCloseReportCh {
 		close(pt.reportCh)
@@ -118,5 +429,7 @@ func (pt *PsTag) Stop() {
 }
 
 func (pt *PsTag) Running() bool {
+	pt.lock.RLock()
+	defer pt.lock.RUnlock()
 	return pt.isRunning
 }
@@ -0,0 +1,73 @@
+package pstag
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stallOutlet struct {
+	release chan struct{}
+}
+
+func (so *stallOutlet) Open() error  { return nil }
+func (so *stallOutlet) Close() error { return nil }
+func (so *stallOutlet) Handle([]*report.Report) error {
+	<-so.release
+	return nil
+}
+
+func TestStalledOutputNotBlockingChanges(t *testing.T) {
+	outlet := &stallOutlet{release: make(chan struct{})}
+	pt := New()
+	require.NoError(t, pt.AddOutput(outlet, WithQueue(1, OverflowBlock)))
+	pt.outputs[0].bufferTimeout = 10 * time.Millisecond
+	require.NoError(t, pt.Run())
+
+	// the outlet stalls at the first flush, then the queue gets full
+	pt.reportCh <- &report.Report{Ts: time.Unix(0, 0)}
+	time.Sleep(50 * time.Millisecond)
+	for i := 1; i < 5; i++ {
+		pt.reportCh <- &report.Report{Ts: time.Unix(int64(i), 0)}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		pt.SetTags([]report.Tag{{Key: "host", Value: "neo"}})
+		pt.Stats()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the changes are blocked by the stalled output")
+	}
+
+	close(outlet.release)
+	pt.Stop()
+}
+
+type openOutlet struct {
+	openErr error
+	closed  bool
+}
+
+func (oo *openOutlet) Open() error                   { return oo.openErr }
+func (oo *openOutlet) Close() error                  { oo.closed = true; return nil }
+func (oo *openOutlet) Handle([]*report.Report) error { return nil }
+
+func TestRunFailure(t *testing.T) {
+	started, failed := &openOutlet{}, &openOutlet{openErr: errors.New("refused")}
+	pt := New(WithInterval(time.Second))
+	require.NoError(t, pt.AddOutput(started, WithOutputName("first")))
+	require.NoError(t, pt.AddOutput(failed, WithOutputName("second")))
+	require.ErrorContains(t, pt.Run(), "refused")
+	require.False(t, pt.Running())
+	// the output that has been started is stopped
+	require.True(t, started.closed)
+}
@@ -0,0 +1,136 @@
+package report
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultNameTemplate places the tag values between the measurement and
+// the field of the record name, e.g. the record "disk.used" with
+// the tag mountpoint=/boot becomes "disk.boot.used".
+const DefaultNameTemplate = "{measurement}.{tags}.{field}"
+
+// Naming flattens a record and its tags into a tag name of machbase-neo.
+//
+// The template can have the placeholders below.
+//
+//	{name}         the name of the record, e.g. "disk.used"
+//	{measurement}  the name before the first dot, e.g. "disk"
+//	{field}        the name after the first dot, e.g. "used"
+//	{tags}         the values of the tags that are not used by {tag:KEY}, joined by dot
+//	{tag:KEY}      the value of the tag KEY
+//
+// A separator next to an empty placeholder is omitted,
+// so the record "cpu.percent" without tags becomes "cpu.percent".
+// The tag values are cleaned by safeTagValue(), so that the mount points
+// and the paths do not put slashes into the names.
+type Naming struct {
+	tmpl  string
+	parts []namePart
+	used  map[string]bool
+}
+
+type namePart struct {
+	literal     string
+	placeholder string
+}
+
+func NewNaming(tmpl string) *Naming {
+	if tmpl == "" {
+		tmpl = DefaultNameTemplate
+	}
+	ret := &Naming{tmpl: tmpl, used: map[string]bool{}}
+	rest := tmpl
+	for len(rest) > 0 {
+		open := strings.Index(rest, "{")
+		close := strings.Index(rest, "}")
+		if open < 0 || close < open {
+			ret.parts = append(ret.parts, namePart{literal: rest})
+			break
+		}
+		if open > 0 {
+			ret.parts = append(ret.parts, namePart{literal: rest[:open]})
+		}
+		ph := rest[open+1 : close]
+		if key, ok := strings.CutPrefix(ph, "tag:"); ok {
+			ret.used[key] = true
+		}
+		ret.parts = append(ret.parts, namePart{placeholder: ph})
+		rest = rest[close+1:]
+	}
+	return ret
+}
+
+func (n *Naming) Template() string {
+	return n.tmpl
+}
+
+// Name returns the flattened name of the record.
+func (n *Naming) Name(rec *Record) string {
+	measurement, field, _ := strings.Cut(rec.Name, ".")
+	out := []string{}
+	lastLiteral := true // the beginning is treated as a separator
+	skipNext := false
+	for _, p := range n.parts {
+		if p.placeholder == "" {
+			if skipNext && lastLiteral {
+				skipNext = false
+				continue
+			}
+			skipNext = false
+			out = append(out, p.literal)
+			lastLiteral = true
+			continue
+		}
+		var v string
+		switch p.placeholder {
+		case "name":
+			v = rec.Name
+		case "measurement":
+			v = measurement
+		case "field":
+			v = field
+		case "tags":
+			vals := []string{}
+			for _, t := range rec.Tags {
+				if !n.used[t.Key] && t.Value != "" {
+					vals = append(vals, safeTagValue(t.Value))
+				}
+			}
+			v = strings.Join(vals, ".")
+		default:
+			if key, ok := strings.CutPrefix(p.placeholder, "tag:"); ok {
+				v = safeTagValue(rec.Tag(key))
+			}
+		}
+		if v == "" {
+			skipNext = true
+			continue
+		}
+		out = append(out, v)
+		lastLiteral = false
+	}
+	// drop the trailing separator of the empty placeholders
+	if skipNext && lastLiteral && len(out) > 0 {
+		out = out[:len(out)-1]
+	}
+	return strings.Join(out, "")
+}
+
+// safeTagValue returns the tag value to be a part of a name,
+// e.g. "/" becomes "root", "/mnt/data" becomes "mnt_data".
+func safeTagValue(v string) string {
+	if v == "" {
+		return ""
+	}
+	v = strings.Trim(v, "/")
+	if v == "" {
+		return "root"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, v)
+}
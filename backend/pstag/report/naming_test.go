@@ -0,0 +1,34 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNaming(t *testing.T) {
+	disk := &Record{Name: "neo_disk.used", Tags: []Tag{{Key: "mountpoint", Value: "/"}, {Key: "host", Value: "edge01"}}}
+	cpu := &Record{Name: "neo_cpu.percent"}
+	load := &Record{Name: "load1"}
+	data := &Record{Name: "disk.used", Tags: []Tag{{Key: "mountpoint", Value: "/mnt/data disk/"}}}
+
+	tests := []struct {
+		tmpl   string
+		rec    *Record
+		expect string
+	}{
+		{"", disk, "neo_disk.root.edge01.used"},
+		{"", data, "disk.mnt_data_disk.used"},
+		{"", cpu, "neo_cpu.percent"},
+		{"", load, "load1"},
+		{"{name}", disk, "neo_disk.used"},
+		{"{tag:host}.{name}", disk, "edge01.neo_disk.used"},
+		{"{tag:host}.{name}", cpu, "neo_cpu.percent"},
+		{"{measurement}.{tags}.{field}.{tag:host}", disk, "neo_disk.root.used.edge01"},
+		{"{tag:mountpoint}.{name}", data, "mnt_data_disk.disk.used"},
+		{"{measurement}_{field}", load, "load1"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expect, NewNaming(tt.tmpl).Name(tt.rec), tt.tmpl)
+	}
+}
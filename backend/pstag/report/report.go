@@ -1,9 +1,15 @@
 package report
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
+// Inlet collects the records. The ctx is done when the deadline
+// of the collection is passed or the input is stopped,
+// Handle should return as soon as possible then.
 type Inlet interface {
-	Handle() ([]*Record, error)
+	Handle(ctx context.Context) ([]*Record, error)
 	Open() error
 	Close() error
 }
@@ -14,6 +20,22 @@ type Outlet interface {
 	Close() error
 }
 
+// Processor transforms the reports between the inlets and the outlets.
+// It may drop, modify or add records, and may return reports
+// of other timestamps than the given ones.
+type Processor interface {
+	Handle(r []*Report) ([]*Report, error)
+	Open() error
+	Close() error
+}
+
+// Flusher is a Processor that holds the records, e.g. of the windows
+// that are not completed yet. Flush returns the pending reports,
+// it is called before the processor is closed or replaced.
+type Flusher interface {
+	Flush() []*Report
+}
+
 type Report struct {
 	Ts      time.Time `json:"ts"`
 	Records []*Record `json:"records,omitempty"`
@@ -23,4 +45,34 @@ type Record struct {
 	Name      string  `json:"name"`
 	Value     float64 `json:"value"`
 	Precision int     `json:"prec,omitempty"`
+	Tags      []Tag   `json:"tags,omitempty"`
+}
+
+// Tag is a dimension of the record, e.g. mountpoint, device, interface.
+// Tags are kept in the order they were added, so that the names
+// flattened from the tags are stable.
+type Tag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Tag returns the value of the tag, or empty string if the tag does not exist.
+func (r *Record) Tag(key string) string {
+	for _, t := range r.Tags {
+		if t.Key == key {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// SetTag replaces the value of the tag, or adds the tag if it does not exist.
+func (r *Record) SetTag(key, value string) {
+	for i, t := range r.Tags {
+		if t.Key == key {
+			r.Tags[i].Value = value
+			return
+		}
+	}
+	r.Tags = append(r.Tags, Tag{Key: key, Value: value})
 }
@@ -0,0 +1,112 @@
+package pstag
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy is the policy that retries a failed outlet with
+// the exponential backoff and jitter.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   5 * time.Second,
+}
+
+// Backoff returns the delay before the retry of the attempt (0-based).
+// The delay is chosen randomly between the half and the full of
+// the exponential delay.
+func (rp RetryPolicy) Backoff(attempt int) time.Duration {
+	d := rp.BaseDelay
+	for i := 0; i < attempt && (rp.MaxDelay <= 0 || d < rp.MaxDelay); i++ {
+		d *= 2
+	}
+	if rp.MaxDelay > 0 && d > rp.MaxDelay {
+		d = rp.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops calling an outlet that failed threshold times in a row.
+// After the cooldown, it lets a trial call go through (half-open),
+// and closes again if the trial succeeds.
+type CircuitBreaker struct {
+	sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     BreakerClosed,
+	}
+}
+
+// Allow reports whether a call can be made.
+func (cb *CircuitBreaker) Allow() bool {
+	cb.Lock()
+	defer cb.Unlock()
+	if cb.state == BreakerOpen {
+		if time.Since(cb.openedAt) < cb.cooldown {
+			return false
+		}
+		cb.state = BreakerHalfOpen
+	}
+	return true
+}
+
+func (cb *CircuitBreaker) Success() {
+	cb.Lock()
+	defer cb.Unlock()
+	cb.failures = 0
+	cb.state = BreakerClosed
+}
+
+func (cb *CircuitBreaker) Failure() {
+	cb.Lock()
+	defer cb.Unlock()
+	cb.failures++
+	if cb.state == BreakerHalfOpen || (cb.threshold > 0 && cb.failures >= cb.threshold) {
+		cb.state = BreakerOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.Lock()
+	defer cb.Unlock()
+	return cb.state
+}
+
+func (cb *CircuitBreaker) Failures() int {
+	cb.Lock()
+	defer cb.Unlock()
+	return cb.failures
+}
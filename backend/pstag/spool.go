@@ -0,0 +1,160 @@
+package pstag
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+)
+
+const spoolFileExt = ".json"
+
+// Spool is a directory of batches that could not be delivered to an outlet.
+// Each batch is written into its own file, named by a sequence number,
+// so that the batches are given back in the order they were pushed.
+type Spool struct {
+	sync.Mutex
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+	seq     uint64
+	files   []spoolFile
+	size    int64
+}
+
+type spoolFile struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func NewSpool(dir string, maxSize int64, maxAge time.Duration) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	ret := &Spool{dir: dir, maxSize: maxSize, maxAge: maxAge}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, ent := range entries {
+		if ent.IsDir() || !strings.HasSuffix(ent.Name(), spoolFileExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(ent.Name(), spoolFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := ent.Info()
+		if err != nil {
+			continue
+		}
+		if seq >= ret.seq {
+			ret.seq = seq + 1
+		}
+		ret.files = append(ret.files, spoolFile{name: ent.Name(), size: info.Size(), modTime: info.ModTime()})
+		ret.size += info.Size()
+	}
+	slices.SortFunc(ret.files, func(a, b spoolFile) int { return strings.Compare(a.name, b.name) })
+	ret.prune()
+	return ret, nil
+}
+
+// Len returns the number of batches in the spool.
+func (sp *Spool) Len() int {
+	sp.Lock()
+	defer sp.Unlock()
+	sp.prune()
+	return len(sp.files)
+}
+
+// Push writes the batch at the tail of the spool.
+func (sp *Spool) Push(rpts []*report.Report) error {
+	data, err := json.Marshal(rpts)
+	if err != nil {
+		return err
+	}
+
+	sp.Lock()
+	defer sp.Unlock()
+
+	name := fmt.Sprintf("%020d%s", sp.seq, spoolFileExt)
+	tmp := filepath.Join(sp.dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(sp.dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	sp.seq++
+	sp.files = append(sp.files, spoolFile{name: name, size: int64(len(data)), modTime: time.Now()})
+	sp.size += int64(len(data))
+	sp.prune()
+	return nil
+}
+
+// Peek returns the oldest batch in the spool.
+// It returns an empty name if the spool is empty.
+func (sp *Spool) Peek() (string, []*report.Report, error) {
+	sp.Lock()
+	defer sp.Unlock()
+	sp.prune()
+	if len(sp.files) == 0 {
+		return "", nil, nil
+	}
+	name := sp.files[0].name
+	data, err := os.ReadFile(filepath.Join(sp.dir, name))
+	if err != nil {
+		return name, nil, err
+	}
+	ret := []*report.Report{}
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return name, nil, err
+	}
+	return name, ret, nil
+}
+
+// Remove deletes the batch that was returned by Peek.
+func (sp *Spool) Remove(name string) error {
+	sp.Lock()
+	defer sp.Unlock()
+	idx := slices.IndexFunc(sp.files, func(f spoolFile) bool { return f.name == name })
+	if idx < 0 {
+		return nil
+	}
+	sp.removeAt(idx)
+	return nil
+}
+
+// prune discards the oldest batches that exceed the size or the age limit.
+func (sp *Spool) prune() {
+	for len(sp.files) > 0 {
+		f := sp.files[0]
+		if sp.maxSize > 0 && sp.size > sp.maxSize {
+			slog.Warn("spool size exceeded, discard", "dir", sp.dir, "file", f.name)
+		} else if sp.maxAge > 0 && time.Since(f.modTime) > sp.maxAge {
+			slog.Warn("spool age exceeded, discard", "dir", sp.dir, "file", f.name)
+		} else {
+			return
+		}
+		sp.removeAt(0)
+	}
+}
+
+func (sp *Spool) removeAt(idx int) {
+	f := sp.files[idx]
+	if err := os.Remove(filepath.Join(sp.dir, f.name)); err != nil && !os.IsNotExist(err) {
+		slog.Error("failed to remove spool", "file", f.name, "error", err.Error())
+	}
+	sp.size -= f.size
+	sp.files = slices.Delete(sp.files, idx, idx+1)
+}
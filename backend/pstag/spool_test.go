@@ -0,0 +1,43 @@
+package pstag
+
+import (
+	"testing"
+	"time"
+
+	"neo-cat/backend/pstag/report"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	ts := time.Unix(1700000000, 123456789)
+
+	sp, err := NewSpool(dir, 0, 0)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		err := sp.Push([]*report.Report{{Ts: ts.Add(time.Duration(i) * time.Second), Records: []*report.Record{{Name: "cpu.percent", Value: float64(i)}}}})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, sp.Len())
+
+	// reopen, batches are kept in order with the original timestamps
+	sp, err = NewSpool(dir, 0, 0)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		name, rpts, err := sp.Peek()
+		require.NoError(t, err)
+		require.Len(t, rpts, 1)
+		require.True(t, ts.Add(time.Duration(i)*time.Second).Equal(rpts[0].Ts))
+		require.Equal(t, float64(i), rpts[0].Records[0].Value)
+		require.NoError(t, sp.Remove(name))
+	}
+	name, _, _ := sp.Peek()
+	require.Equal(t, "", name)
+
+	// size limit discards the oldest
+	sp, err = NewSpool(t.TempDir(), 1, 0)
+	require.NoError(t, err)
+	require.NoError(t, sp.Push([]*report.Report{{Ts: ts}}))
+	require.Equal(t, 0, sp.Len())
+}
@@ -0,0 +1,105 @@
+package pstag
+
+import (
+	"context"
+
+	"neo-cat/backend/pstag/report"
+)
+
+// SelfStatsInput is the name of the input that reports the stats of the PsTag itself.
+const SelfStatsInput = "neocat"
+
+type Stats struct {
+	// QueueDepth is the number of reports waiting to be processed.
+	QueueDepth int           `json:"queue_depth"`
+	Inputs     []InputStats  `json:"inputs"`
+	Outputs    []OutputStats `json:"outputs"`
+}
+
+func (pt *PsTag) Stats() Stats {
+	pt.lock.RLock()
+	defer pt.lock.RUnlock()
+	ret := Stats{
+		QueueDepth: len(pt.reportCh),
+		Inputs:     make([]InputStats, 0, len(pt.inputs)),
+		Outputs:    make([]OutputStats, 0, len(pt.outputs)),
+	}
+	for _, in := range pt.inputs {
+		ret.Inputs = append(ret.Inputs, in.Stats())
+	}
+	for _, out := range pt.outputs {
+		ret.Outputs = append(ret.Outputs, out.Stats())
+	}
+	return ret
+}
+
+// WithSelfStats reports the stats of the inputs and the outputs
+// as neocat.* records along with the other records.
+func WithSelfStats(enable bool) Option {
+	return func(pt *PsTag) {
+		pt.selfStats = enable
+	}
+}
+
+// SetSelfStats adds or removes the input of the self stats.
+func (pt *PsTag) SetSelfStats(enable bool) error {
+	pt.lock.RLock()
+	exists := pt.selfStats
+	pt.lock.RUnlock()
+	if exists == enable {
+		return nil
+	}
+	var err error
+	if enable {
+		err = pt.AddInput(&selfInlet{pt: pt}, WithInputName(SelfStatsInput))
+	} else {
+		err = pt.RemoveInput(SelfStatsInput)
+	}
+	if err != nil {
+		return err
+	}
+	pt.lock.Lock()
+	pt.selfStats = enable
+	pt.lock.Unlock()
+	return nil
+}
+
+// selfInlet reports the stats as records, the counts are cumulative.
+type selfInlet struct {
+	pt *PsTag
+}
+
+var _ report.Inlet = (*selfInlet)(nil)
+
+func (si *selfInlet) Open() error  { return nil }
+func (si *selfInlet) Close() error { return nil }
+
+func (si *selfInlet) Handle(ctx context.Context) ([]*report.Record, error) {
+	stats := si.pt.Stats()
+	ret := []*report.Record{
+		{Name: "neocat.queue_depth", Value: float64(stats.QueueDepth)},
+	}
+	for _, in := range stats.Inputs {
+		tags := []report.Tag{{Key: "input", Value: in.Name}}
+		ret = append(ret,
+			&report.Record{Name: "neocat.input.duration_ms", Value: in.LastDuration, Precision: 2, Tags: tags},
+			&report.Record{Name: "neocat.input.records", Value: float64(in.Records), Tags: tags},
+			&report.Record{Name: "neocat.input.errors", Value: float64(in.Errors), Tags: tags},
+			&report.Record{Name: "neocat.input.timeouts", Value: float64(in.Timeouts), Tags: tags},
+			&report.Record{Name: "neocat.input.overruns", Value: float64(in.Overruns), Tags: tags},
+		)
+	}
+	for _, out := range stats.Outputs {
+		tags := []report.Tag{{Key: "output", Value: out.Name}}
+		ret = append(ret,
+			&report.Record{Name: "neocat.output.duration_ms", Value: out.LastDuration, Precision: 2, Tags: tags},
+			&report.Record{Name: "neocat.output.records", Value: float64(out.Records), Tags: tags},
+			&report.Record{Name: "neocat.output.errors", Value: float64(out.Errors), Tags: tags},
+			&report.Record{Name: "neocat.output.dropped", Value: float64(out.Dropped), Tags: tags},
+			&report.Record{Name: "neocat.output.queue_depth", Value: float64(out.QueueDepth), Tags: tags},
+			&report.Record{Name: "neocat.output.spooled", Value: float64(out.Spooled), Tags: tags},
+			&report.Record{Name: "neocat.output.consecutive_failures", Value: float64(out.ConsecutiveFailures), Tags: tags},
+		)
+	}
+	return ret, nil
+}
@@ -8,11 +8,13 @@ import (
 	"io"
 	"neo-cat/backend/model"
 	"neo-cat/backend/pstag"
+	"neo-cat/backend/pstag/plugin"
 	"neo-cat/backend/store"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -78,7 +80,8 @@ type Server struct {
 	lsnr           net.Listener
 	data           *store.Store
 	stopOnce       sync.Once
-	process        *pstag.PsTag
+	pipelines      map[string]*pipeline
+	processLock    sync.Mutex
 	sid            *shortid.Shortid
 	sidTable       map[string]time.Time
 	sidLock        sync.RWMutex
@@ -156,9 +159,7 @@ func (s *Server) Start() error {
 	r := s.router()
 	s.httpd.Handler = r
 
-	// ignore returned error
-	// because it may be not configured yet.
-	s.StartProcess()
+	s.StartPipelines()
 
 	go s.httpd.Serve(s.lsnr)
 	return nil
@@ -166,9 +167,7 @@ func (s *Server) Start() error {
 
 func (s *Server) Stop() {
 	s.stopOnce.Do(func() {
-		if s.process != nil && s.process.Running() {
-			s.process.Stop()
-		}
+		s.StopPipelines()
 		if s.httpd != nil {
 			s.httpd.Close()
 		}
@@ -224,6 +223,13 @@ func (s *Server) router() *gin.Engine {
 	group.GET("/configs/:key", s.getConfig)
 	group.DELETE("/configs/:key", s.deleteConfig)
 	group.GET("/control/:act", s.getControl)
+	group.GET("/health", s.getHealth)
+	group.GET("/stats", s.getStats)
+	group.GET("/plugins", s.getPlugins)
+	group.GET("/pipelines", s.getPipelines)
+	group.GET("/pipelines/:name", s.getPipeline)
+	group.POST("/pipelines/:name", s.postPipeline)
+	group.DELETE("/pipelines/:name", s.deletePipeline)
 	if s.debugMode {
 		// route to machbase-neo for development
 		if dbProxy == nil {
@@ -387,6 +393,11 @@ func (s *Server) postConfigs(c *gin.Context) {
 			return
 		}
 	}
+	if err := s.ReloadProcess(DefaultPipeline); err != nil {
+		rsp.Reason = err.Error()
+		c.JSON(500, rsp)
+		return
+	}
 	rsp.Success, rsp.Reason = true, "success"
 	c.JSON(200, rsp)
 }
@@ -424,46 +435,226 @@ func (s *Server) deleteConfig(c *gin.Context) {
 		c.JSON(500, rsp)
 		return
 	}
+	if err := s.ReloadProcess(DefaultPipeline); err != nil {
+		rsp.Reason = err.Error()
+		c.JSON(500, rsp)
+		return
+	}
 	rsp.Success, rsp.Reason = true, "success"
 	c.JSON(200, rsp)
 }
 
+func (s *Server) pipelineStatus(name string) string {
+	if s.runningProcess(name) != nil {
+		return "running"
+	}
+	return "stopped"
+}
+
 func (s *Server) getControl(c *gin.Context) {
 	rsp := &Response{}
 	act := c.Param("act")
+	name := c.DefaultQuery("pipeline", DefaultPipeline)
 	switch act {
 	case "start":
-		if err := s.StartProcess(); err != nil {
+		if err := s.StartProcess(name); err != nil {
 			rsp.Reason = err.Error()
 			c.JSON(500, rsp)
 			return
 		}
 		rsp.Success, rsp.Reason = true, "success"
-		if s.process != nil && s.process.Running() {
-			rsp.Data = gin.H{"status": "running"}
-		} else {
-			rsp.Data = gin.H{"status": "stopped"}
-		}
+		rsp.Data = gin.H{"status": s.pipelineStatus(name)}
 		c.JSON(200, rsp)
 	case "stop":
-		s.StopProcess()
+		s.StopProcess(name)
 		rsp.Success, rsp.Reason = true, "success"
-		if s.process != nil && s.process.Running() {
-			rsp.Data = gin.H{"status": "running"}
-		} else {
-			rsp.Data = gin.H{"status": "stopped"}
-		}
+		rsp.Data = gin.H{"status": s.pipelineStatus(name)}
 		c.JSON(200, rsp)
 	case "status":
 		rsp.Success, rsp.Reason = true, "success"
-		if s.process != nil && s.process.Running() {
-			rsp.Data = gin.H{"status": "running"}
-		} else {
-			rsp.Data = gin.H{"status": "stopped"}
-		}
+		rsp.Data = gin.H{"status": s.pipelineStatus(name)}
 		c.JSON(200, rsp)
 	default:
 		rsp.Reason = fmt.Sprintf("unknown action %q", act)
 		c.JSON(400, rsp)
 	}
 }
+
+func (s *Server) getHealth(c *gin.Context) {
+	rsp := &Response{}
+	outlets := []pstag.OutputHealth{}
+	if process := s.runningProcess(c.DefaultQuery("pipeline", DefaultPipeline)); process != nil {
+		outlets = process.OutputHealth()
+	}
+	rsp.Success, rsp.Reason = true, "success"
+	rsp.Data = gin.H{"outlets": outlets}
+	c.JSON(200, rsp)
+}
+
+func (s *Server) getStats(c *gin.Context) {
+	rsp := &Response{}
+	stats := pstag.Stats{Inputs: []pstag.InputStats{}, Outputs: []pstag.OutputStats{}}
+	if process := s.runningProcess(c.DefaultQuery("pipeline", DefaultPipeline)); process != nil {
+		stats = process.Stats()
+	}
+	rsp.Success, rsp.Reason = true, "success"
+	rsp.Data = stats
+	c.JSON(200, rsp)
+}
+
+// getPlugins lists the inlets, processors and outlets with the schemas of their args.
+func (s *Server) getPlugins(c *gin.Context) {
+	rsp := &Response{}
+	rsp.Success, rsp.Reason = true, "success"
+	rsp.Data = plugin.GetCatalog()
+	c.JSON(200, rsp)
+}
+
+var pipelineNameRegexp = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+type PipelineStatus struct {
+	Name       string                 `json:"name"`
+	Status     string                 `json:"status"`
+	Configs    map[string]string      `json:"configs,omitempty"`
+	Inputs     []model.PluginInstance `json:"inputs,omitempty"`
+	Processors []model.PluginInstance `json:"processors,omitempty"`
+	Outputs    []model.PluginInstance `json:"outputs,omitempty"`
+}
+
+func newPipelineStatus(p *model.Pipeline, status string) PipelineStatus {
+	return PipelineStatus{
+		Name:       p.Name,
+		Status:     status,
+		Configs:    p.Configs,
+		Inputs:     p.Inputs,
+		Processors: p.Processors,
+		Outputs:    p.Outputs,
+	}
+}
+
+func (s *Server) getPipelines(c *gin.Context) {
+	rsp := &Response{}
+	list, err := s.data.GetPipelines()
+	if err != nil {
+		rsp.Reason = err.Error()
+		c.JSON(500, rsp)
+		return
+	}
+	// the configs of the default pipeline are the global configs
+	def := PipelineStatus{Name: DefaultPipeline, Status: s.pipelineStatus(DefaultPipeline)}
+	ret := []PipelineStatus{def}
+	for _, p := range list {
+		if p.Name == DefaultPipeline {
+			def.Inputs, def.Processors, def.Outputs = p.Inputs, p.Processors, p.Outputs
+			ret[0] = def
+			continue
+		}
+		ret = append(ret, newPipelineStatus(p, s.pipelineStatus(p.Name)))
+	}
+	rsp.Success, rsp.Reason = true, "success"
+	rsp.Data = ret
+	c.JSON(200, rsp)
+}
+
+func (s *Server) getPipeline(c *gin.Context) {
+	rsp := &Response{}
+	name := strings.ToLower(c.Param("name"))
+	p, err := s.loadPipeline(name)
+	if err != nil {
+		rsp.Reason = err.Error()
+		c.JSON(404, rsp)
+		return
+	}
+	rsp.Success, rsp.Reason = true, "success"
+	rsp.Data = newPipelineStatus(p, s.pipelineStatus(name))
+	c.JSON(200, rsp)
+}
+
+// postPipeline creates or replaces the definition of the pipeline,
+// that is the configs and the plugin instances of the inputs, processors and outputs.
+// The default pipeline takes only the plugin instances, its configs are the global configs,
+// and it falls back to the plugins of the configs if none of them is given.
+// The running pipeline applies the changes immediately.
+func (s *Server) postPipeline(c *gin.Context) {
+	rsp := &Response{}
+	name := strings.ToLower(c.Param("name"))
+	if !pipelineNameRegexp.MatchString(name) {
+		rsp.Reason = fmt.Sprintf("invalid pipeline name %q", name)
+		c.JSON(400, rsp)
+		return
+	}
+	req := &model.Pipeline{}
+	if err := c.Bind(req); err != nil {
+		rsp.Reason = err.Error()
+		c.JSON(400, rsp)
+		return
+	}
+	p := &model.Pipeline{Name: name, Configs: map[string]string{},
+		Inputs: req.Inputs, Processors: req.Processors, Outputs: req.Outputs}
+	for k, v := range req.Configs {
+		p.Configs[strings.ToLower(k)] = v
+	}
+	check := p
+	if name == DefaultPipeline {
+		if len(req.Configs) > 0 {
+			rsp.Reason = "the configs of the default pipeline are the global configs"
+			c.JSON(400, rsp)
+			return
+		}
+		p.Configs = nil
+		configs, err := s.data.GetConfigs()
+		if err != nil {
+			rsp.Reason = err.Error()
+			c.JSON(500, rsp)
+			return
+		}
+		check = &model.Pipeline{Name: name, Configs: configs,
+			Inputs: p.Inputs, Processors: p.Processors, Outputs: p.Outputs}
+	}
+	if _, err := s.buildProcessSpec(check); err != nil {
+		rsp.Reason = err.Error()
+		c.JSON(400, rsp)
+		return
+	}
+	if err := s.data.SetPipeline(p); err != nil {
+		rsp.Reason = err.Error()
+		c.JSON(500, rsp)
+		return
+	}
+	if err := s.ReloadProcess(name); err != nil {
+		rsp.Reason = err.Error()
+		c.JSON(500, rsp)
+		return
+	}
+	rsp.Success, rsp.Reason = true, "success"
+	c.JSON(200, rsp)
+}
+
+func (s *Server) deletePipeline(c *gin.Context) {
+	rsp := &Response{}
+	name := strings.ToLower(c.Param("name"))
+	if name == DefaultPipeline {
+		// the definition is deleted, and it falls back to the plugins of the configs
+		if err := s.data.DeletePipeline(name); err != nil {
+			rsp.Reason = err.Error()
+			c.JSON(500, rsp)
+			return
+		}
+		if err := s.ReloadProcess(name); err != nil {
+			rsp.Reason = err.Error()
+			c.JSON(500, rsp)
+			return
+		}
+		rsp.Success, rsp.Reason = true, "success"
+		c.JSON(200, rsp)
+		return
+	}
+	s.RemoveProcess(name)
+	if err := s.data.DeletePipeline(name); err != nil {
+		rsp.Reason = err.Error()
+		c.JSON(500, rsp)
+		return
+	}
+	rsp.Success, rsp.Reason = true, "success"
+	c.JSON(200, rsp)
+}
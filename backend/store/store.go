@@ -2,6 +2,8 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"neo-cat/backend/model"
 	"strings"
@@ -10,6 +12,9 @@ import (
 	_ "github.com/glebarez/go-sqlite"
 )
 
+// ErrNotFound is the error of the item that is not in the store.
+var ErrNotFound = errors.New("not found")
+
 type Store struct {
 	sync.Mutex
 	db *sql.DB
@@ -62,6 +67,16 @@ func (s *Store) createTables() error {
 		return err
 	}
 
+	query = `
+		CREATE TABLE IF NOT EXISTS pipelines (
+			name TEXT PRIMARY KEY,
+			definition TEXT
+		);
+	`
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -153,3 +168,70 @@ func (s *Store) GetConfigs() (map[string]string, error) {
 	}
 	return ret, nil
 }
+
+// SetPipeline stores the definition of the pipeline as JSON.
+func (s *Store) SetPipeline(p *model.Pipeline) error {
+	s.Lock()
+	defer s.Unlock()
+	definition, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	query := `INSERT OR REPLACE INTO pipelines (name, definition) VALUES (?, ?);`
+	_, err = s.db.Exec(query, p.Name, string(definition))
+	return err
+}
+
+func (s *Store) GetPipeline(name string) (*model.Pipeline, error) {
+	s.Lock()
+	defer s.Unlock()
+	query := `SELECT definition FROM pipelines WHERE name = ?;`
+	var definition string
+	if err := s.db.QueryRow(query, name).Scan(&definition); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("pipeline %q %w", name, ErrNotFound)
+		}
+		return nil, err
+	}
+	ret := &model.Pipeline{}
+	if err := json.Unmarshal([]byte(definition), ret); err != nil {
+		return nil, err
+	}
+	ret.Name = name
+	return ret, nil
+}
+
+func (s *Store) DeletePipeline(name string) error {
+	s.Lock()
+	defer s.Unlock()
+	query := `DELETE FROM pipelines WHERE name = ?;`
+	_, err := s.db.Exec(query, name)
+	return err
+}
+
+func (s *Store) GetPipelines() ([]*model.Pipeline, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	query := `SELECT name, definition FROM pipelines ORDER BY name;`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := []*model.Pipeline{}
+	for rows.Next() {
+		var name, definition string
+		if err := rows.Scan(&name, &definition); err != nil {
+			return nil, err
+		}
+		p := &model.Pipeline{}
+		if err := json.Unmarshal([]byte(definition), p); err != nil {
+			return nil, err
+		}
+		p.Name = name
+		ret = append(ret, p)
+	}
+	return ret, rows.Err()
+}
@@ -57,6 +57,16 @@ export const setConfig = async (key: string, val: string) => {
     });
 }
 
+// backend: setConfigs, the configs are stored at once and the pipeline is reloaded once
+export const setConfigs = async (values: { [key: string]: string }) => {
+    return request({
+        method: 'POST',
+        baseURL: '/web/apps/neo-cat',
+        url: '/api/configs',
+        data: JSON.stringify(values),
+    });
+}
+
 // backend: getConfig
 export const getConfig = async (key: string) => {
     return request({
@@ -134,6 +144,83 @@ export const statusControl = async () => {
     });
 }
 
+// backend: pipelines and their status
+export const getPipelines = async () => {
+    return request({
+        method: 'GET',
+        baseURL: '/web/apps/neo-cat',
+        url: '/api/pipelines',
+    });
+}
+
+export interface PluginInstance {
+    name?: string;
+    plugin: string;
+    args?: string[];
+    options?: { [key: string]: string };
+}
+
+export interface PipelineDefinition {
+    configs?: { [key: string]: string };
+    inputs?: PluginInstance[];
+    processors?: PluginInstance[];
+    outputs?: PluginInstance[];
+}
+
+// backend: create or replace the definition of the pipeline
+export const setPipeline = async (name: string, definition: PipelineDefinition) => {
+    return request({
+        method: 'POST',
+        baseURL: '/web/apps/neo-cat',
+        url: `/api/pipelines/${name}`,
+        data: JSON.stringify(definition),
+    });
+}
+
+// backend: delete the pipeline
+export const deletePipeline = async (name: string) => {
+    return request({
+        method: 'DELETE',
+        baseURL: '/web/apps/neo-cat',
+        url: `/api/pipelines/${name}`,
+    });
+}
+
+export interface ArgSpec {
+    name: string;
+    type: 'string' | 'list' | 'int' | 'float' | 'bool' | 'duration' | 'regexp';
+    required?: boolean;
+    default?: string;
+    enum?: string[];
+    desc?: string;
+    variadic?: boolean;
+    keyword?: boolean;
+}
+
+export interface PluginInfo {
+    name: string;
+    desc: string;
+    args: ArgSpec[];
+}
+
+// backend: inlets, processors and outlets with the schemas of their args
+export const getPlugins = async () => {
+    return request({
+        method: 'GET',
+        baseURL: '/web/apps/neo-cat',
+        url: '/api/plugins',
+    });
+}
+
+// backend: stats of the inputs and the outputs
+export const getStats = async () => {
+    return request({
+        method: 'GET',
+        baseURL: '/web/apps/neo-cat',
+        url: '/api/stats',
+    });
+}
+
 // neo: query
 export const queryTagData = async (table: string, tag: string, durationSec: number) => {
     const tick = Date.now();
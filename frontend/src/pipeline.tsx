@@ -0,0 +1,89 @@
+import { useState, useEffect } from "react"
+import { getStats } from "./api/api.ts";
+import { useTimeout } from "./hooks/useTimeout";
+
+const fmtTime = (ts: string): string => {
+    if (!ts || ts.startsWith('0001-')) {
+        return '-';
+    }
+    return new Date(ts).toLocaleTimeString();
+}
+
+export function PipelineStats(conf: { refreshIntervalSec: number }): any {
+    const [stats, setStats] = useState<any>({ queue_depth: 0, inputs: [], outputs: [] });
+
+    const loadStats = async () => {
+        const rsp: any = await getStats();
+        if (rsp.success) {
+            setStats(rsp.data);
+        }
+    }
+    useEffect(() => { loadStats() }, []);
+    useTimeout(loadStats, conf.refreshIntervalSec * 1000);
+
+    const cell = { padding: 'var(--sl-spacing-2x-small) var(--sl-spacing-small)', textAlign: 'start' as const };
+    return (
+        <div>
+            <h4>Inputs</h4>
+            <table>
+                <thead>
+                    <tr>
+                        <th style={cell}>Name</th>
+                        <th style={cell}>Runs</th>
+                        <th style={cell}>Records</th>
+                        <th style={cell}>Errors</th>
+                        <th style={cell}>Duration(ms)</th>
+                        <th style={cell}>Last success</th>
+                        <th style={cell}>Last error</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    {stats.inputs.map((in_: any) => (
+                        <tr key={in_.name}>
+                            <td style={cell}>{in_.name}</td>
+                            <td style={cell}>{in_.runs}</td>
+                            <td style={cell}>{in_.records}</td>
+                            <td style={cell}>{in_.errors}</td>
+                            <td style={cell}>{in_.last_duration_ms.toFixed(2)}</td>
+                            <td style={cell}>{fmtTime(in_.last_success_time)}</td>
+                            <td style={cell}>{in_.last_error ? `${fmtTime(in_.last_error_time)} ${in_.last_error}` : '-'}</td>
+                        </tr>
+                    ))}
+                </tbody>
+            </table>
+            <h4>Outputs (queue {stats.queue_depth})</h4>
+            <table>
+                <thead>
+                    <tr>
+                        <th style={cell}>Name</th>
+                        <th style={cell}>State</th>
+                        <th style={cell}>Records</th>
+                        <th style={cell}>Errors</th>
+                        <th style={cell}>Dropped</th>
+                        <th style={cell}>Queue</th>
+                        <th style={cell}>Spooled</th>
+                        <th style={cell}>Duration(ms)</th>
+                        <th style={cell}>Last success</th>
+                        <th style={cell}>Last error</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    {stats.outputs.map((out: any) => (
+                        <tr key={out.name}>
+                            <td style={cell}>{out.name}</td>
+                            <td style={cell}>{out.state}</td>
+                            <td style={cell}>{out.records}</td>
+                            <td style={cell}>{out.errors}</td>
+                            <td style={cell}>{out.dropped}</td>
+                            <td style={cell}>{out.queue_depth}/{out.queue_size}</td>
+                            <td style={cell}>{out.spooled}</td>
+                            <td style={cell}>{out.last_duration_ms.toFixed(2)}</td>
+                            <td style={cell}>{fmtTime(out.last_success_time)}</td>
+                            <td style={cell}>{out.last_error ? `${fmtTime(out.last_error_time)} ${out.last_error}` : '-'}</td>
+                        </tr>
+                    ))}
+                </tbody>
+            </table>
+        </div>
+    )
+}
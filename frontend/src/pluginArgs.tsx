@@ -0,0 +1,122 @@
+import SlInput from '@shoelace-style/shoelace/dist/react/input';
+import SlSelect from '@shoelace-style/shoelace/dist/react/select';
+import SlOption from '@shoelace-style/shoelace/dist/react/option';
+import SlCheckbox from '@shoelace-style/shoelace/dist/react/checkbox';
+import type SlInputElement from '@shoelace-style/shoelace/dist/components/input/input';
+import type SlSelectElement from '@shoelace-style/shoelace/dist/components/select/select';
+import type SlCheckboxElement from '@shoelace-style/shoelace/dist/components/checkbox/checkbox';
+import type { ArgSpec, PluginInfo } from './api/api.ts';
+
+// PluginArgs renders the inputs of the args of the plugin by the schema of the catalog,
+// the values are read back by readPluginArgs() on submit.
+export function PluginArgs(conf: { info: PluginInfo, args: string[] }) {
+    const values = splitPluginArgs(conf.info, conf.args);
+    return (
+        <div style={{ paddingLeft: '30px', maxWidth: '400px' }}>
+            {conf.info.args.map((spec) => (
+                <ArgInput key={spec.name} id={argId(conf.info, spec)} spec={spec} value={values.get(spec.name) || ''} />
+            ))}
+        </div>
+    );
+}
+
+function ArgInput(conf: { id: string, spec: ArgSpec, value: string }) {
+    const { id, spec, value } = conf;
+    if (spec.type === 'bool') {
+        return <div><SlCheckbox id={id} checked={value === 'true'}>{spec.desc || spec.name}</SlCheckbox></div>;
+    }
+    if (spec.enum && spec.enum.length > 0) {
+        const multiple = spec.type === 'list';
+        return (
+            <SlSelect
+                id={id}
+                size='small'
+                label={spec.name}
+                helpText={spec.desc}
+                placeholder={spec.default}
+                multiple={multiple}
+                value={multiple ? (value ? value.split(',') : []) : value}
+                clearable={!spec.required}>
+                {spec.enum.map((v) => <SlOption key={v} value={v}>{v}</SlOption>)}
+            </SlSelect>
+        );
+    }
+    const numeric = spec.type === 'int' || spec.type === 'float';
+    return (
+        <SlInput
+            id={id}
+            size='small'
+            label={spec.name}
+            helpText={spec.desc}
+            placeholder={spec.default}
+            type={numeric ? 'number' : 'text'}
+            value={value}
+            required={spec.required} />
+    );
+}
+
+// readPluginArgs returns the args of the inputs rendered by PluginArgs,
+// the positional args in the order of the schema and the keyword args as "<name>=<value>".
+// The positional args that are not given are left out only at the end.
+export function readPluginArgs(info: PluginInfo): string[] {
+    const positional: { spec: ArgSpec, value: string }[] = [];
+    const keywords: string[] = [];
+    for (const spec of info.args) {
+        const value = readArg(argId(info, spec), spec);
+        if (spec.keyword) {
+            if (value !== '') keywords.push(`${spec.name}=${value}`);
+        } else if (spec.variadic) {
+            positional.push(...value.split(',').filter((v) => v !== '').map((v) => ({ spec, value: v })));
+        } else {
+            positional.push({ spec, value });
+        }
+    }
+    while (positional.length > 0 && positional[positional.length - 1].value === '') {
+        positional.pop();
+    }
+    // the gaps before the given args are filled with the defaults
+    return [...positional.map((p) => p.value !== '' ? p.value : (p.spec.default || '')), ...keywords];
+}
+
+function readArg(id: string, spec: ArgSpec): string {
+    const el = document.getElementById(id);
+    if (!el) return '';
+    if (spec.type === 'bool') {
+        return (el as SlCheckboxElement).checked ? 'true' : 'false';
+    }
+    if (spec.enum && spec.enum.length > 0) {
+        const value = (el as SlSelectElement).value;
+        return Array.isArray(value) ? value.join(',') : (value as string).trim();
+    }
+    return ((el as SlInputElement).value as string).trim();
+}
+
+// splitPluginArgs maps the args to the names of the schema as the backend validates them.
+export function splitPluginArgs(info: PluginInfo, args: string[]): Map<string, string> {
+    const ret = new Map<string, string>();
+    const positional: string[] = [];
+    for (const arg of args || []) {
+        const idx = arg.indexOf('=');
+        const spec = idx > 0 ? info.args.find((s) => s.keyword && s.name === arg.substring(0, idx)) : undefined;
+        if (spec) {
+            ret.set(spec.name, arg.substring(idx + 1));
+        } else {
+            positional.push(arg);
+        }
+    }
+    let i = 0;
+    for (const spec of info.args) {
+        if (spec.keyword || i >= positional.length) continue;
+        if (spec.variadic) {
+            ret.set(spec.name, positional.slice(i).join(','));
+            i = positional.length;
+        } else {
+            ret.set(spec.name, positional[i++]);
+        }
+    }
+    return ret;
+}
+
+function argId(info: PluginInfo, spec: ArgSpec): string {
+    return `arg-${info.name}-${spec.name}`;
+}
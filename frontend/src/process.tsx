@@ -19,6 +19,7 @@ import { SystemChart, NeoChart } from './chart.tsx';
 import { StatusButton } from './status.tsx';
 import { Settings } from './setup.tsx';
 import { InputSettings } from './setupInputs.tsx';
+import { PipelineStats } from './pipeline.tsx';
 
 const PKG_RUNNING = 'running';
 const PKG_STOPPED = 'stopped';
@@ -275,6 +276,7 @@ export function ProcessControl() {
                         </style>
                         <SlTab slot="nav" panel="system">System</SlTab>
                         <SlTab slot="nav" panel="machbase">Machbase</SlTab>
+                        <SlTab slot="nav" panel="pipeline">Pipeline</SlTab>
                         <SlTabPanel name="system">
                             <SystemChart
                                 tableName={configuredTableName}
@@ -298,6 +300,9 @@ export function ProcessControl() {
                                 inTableRowsCounter={sInputTableRowsCounter}
                             />
                         </SlTabPanel>
+                        <SlTabPanel name="pipeline">
+                            <PipelineStats refreshIntervalSec={chartRefreshSec} />
+                        </SlTabPanel>
                     </SlTabGroup>
                     :
                     <div>...</div>
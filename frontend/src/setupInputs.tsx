@@ -1,8 +1,16 @@
 import { useEffect, useState } from 'react';
+import type { FormEvent } from 'react';
 import SlButton from '@shoelace-style/shoelace/dist/react/button';
+import SlInput from '@shoelace-style/shoelace/dist/react/input';
 import SlCheckbox from '@shoelace-style/shoelace/dist/react/checkbox';
+import SlSelect from '@shoelace-style/shoelace/dist/react/select';
+import SlOption from '@shoelace-style/shoelace/dist/react/option';
 import type SlCheckboxElement from '@shoelace-style/shoelace/dist/components/checkbox/checkbox';
-import { getConfig, setConfig, getMachine, getDBTables } from './api/api.ts';
+import type SlInputElement from '@shoelace-style/shoelace/dist/components/input/input';
+import type SlSelectElement from '@shoelace-style/shoelace/dist/components/select/select';
+import { getConfig, setConfigs, getMachine, getDBTables, getPlugins } from './api/api.ts';
+import type { PluginInfo } from './api/api.ts';
+import { PluginArgs, readPluginArgs } from './pluginArgs.tsx';
 
 export function InputSettings() {
     const [sProtocol, setProto] = useState<string[]>(null);
@@ -23,6 +31,12 @@ export function InputSettings() {
     const [sItemsNet, setItemsNet] = useState<string[]>(null);
     const [sItemsRowsCounter, setItemsRowsCounter] = useState<string[]>(null);
 
+    const [sIntervals, setIntervals] = useState<Map<string, string>>(null);
+    const [sDiskFilters, setDiskFilters] = useState<Map<string, string>>(null);
+
+    const [sOptionalInlets, setOptionalInlets] = useState<PluginInfo[]>(null);
+    const [sOptionalValues, setOptionalValues] = useState<Map<string, string>>(null);
+
     const loadConfig = async () => {
         const inProto: any = await getConfig('in_proto')
         if (inProto.success) {
@@ -44,11 +58,71 @@ export function InputSettings() {
         if (inRowsCounter.success) {
             setRowsCounter(inRowsCounter.data.in_table_rows_counter.split(','));
         }
+        const intervals = new Map<string, string>();
+        for (const kind of INTERVAL_KINDS) {
+            const key = `in_${kind}_interval`;
+            const rsp: any = await getConfig(key);
+            intervals.set(kind, rsp.success && rsp.data[key] ? rsp.data[key] : '');
+        }
+        setIntervals(intervals);
+        const diskFilters = new Map<string, string>();
+        for (const filter of DISK_FILTERS) {
+            const rsp: any = await getConfig(filter.key);
+            diskFilters.set(filter.key, rsp.success && rsp.data[filter.key] ? rsp.data[filter.key] : '');
+        }
+        setDiskFilters(diskFilters);
     }
     useEffect(() => {
         loadConfig();
     }, []);
 
+    // all the configs of the form are stored at once, so the pipeline is reloaded once
+    const onSubmit = async (event: FormEvent<HTMLFormElement>) => {
+        event.preventDefault();
+        const values: { [key: string]: string } = {};
+        const lists: [string, string[]][] = [
+            ['disk', sOptionDisk], ['diskio', sOptionDiskio], ['net', sOptionNet],
+            ['proto', sOptionProtocol], ['table_rows_counter', sOptionRowsCounter],
+        ];
+        for (const [kind, items] of lists) {
+            if (!items) continue;
+            values[`in_${kind}`] = readCheckboxList(kind, items).join(',');
+        }
+        for (const kind of INTERVAL_KINDS) {
+            const sel = document.getElementById(`interval-${kind}`) as SlSelectElement;
+            if (!sel) continue;
+            values[`in_${kind}_interval`] = sel.value as string;
+        }
+        for (const filter of DISK_FILTERS) {
+            const input = document.getElementById(`disk-filter-${filter.key}`) as SlInputElement;
+            if (!input) continue;
+            values[filter.key] = (input.value as string).trim();
+        }
+        for (const info of sOptionalInlets || []) {
+            const key = OPTIONAL_INLETS.get(info.name);
+            const cb = document.getElementById(`optional-${key}`) as SlCheckboxElement;
+            if (!cb) continue;
+            const args = readPluginArgs(info);
+            values[key] = !cb.checked ? 'false' : args.length > 0 ? args.join(' ') : 'true';
+        }
+        await setConfigs(values);
+    };
+
+    useEffect(() => {
+        if (sOptionalInlets) return;
+        getPlugins().then(async (rsp: any) => {
+            if (!rsp || !rsp.success || !rsp.data || !rsp.data.inlets) return;
+            const infos: PluginInfo[] = rsp.data.inlets.filter((info: PluginInfo) => OPTIONAL_INLETS.has(info.name));
+            const values = new Map<string, string>();
+            for (const info of infos) {
+                const key = OPTIONAL_INLETS.get(info.name);
+                const rsp: any = await getConfig(key);
+                values.set(key, rsp.success && rsp.data[key] ? rsp.data[key] : '');
+            }
+            setOptionalValues(values);
+            setOptionalInlets(infos);
+        })
+    }, [sOptionalInlets]);
     useEffect(() => {
         if (sOptionProtocol) return;
         getMachine('protocol').then((rsp: any) => {
@@ -62,7 +136,11 @@ export function InputSettings() {
         getMachine('partition').then((rsp: any) => {
             if (!rsp || !rsp.success || !rsp.data || !rsp.data.partition) return;
             setOptionDisk(rsp.data.partition);
-            setItemsDisk(makeCheckboxList('disk', rsp.data.partition, sDisk));
+            const captions = new Map<string, string>();
+            for (const p of rsp.data.partitions || []) {
+                captions.set(p.mountpoint, `${p.mountpoint} (${p.fstype})`);
+            }
+            setItemsDisk(makeCheckboxList('disk', rsp.data.partition, sDisk, captions));
         })
     }, [sOptionDisk, sDisk]);
     useEffect(() => {
@@ -100,60 +178,131 @@ export function InputSettings() {
         })
     }, [sOptionRowsCounter, sRowsCounter]);
     return (
-        <form id='inputs-form'>
+        <form id='inputs-form' onSubmit={onSubmit}>
             Disk Usages
+            <IntervalSelect kind='disk' intervals={sIntervals} />
             <div style={{ paddingLeft: '30px', paddingBottom: '20px' }}>
                 {sItemsDisk && sItemsDisk.map((opt) => opt)}
             </div>
+            <DiskFilters values={sDiskFilters} />
 
             Disk IO
+            <IntervalSelect kind='diskio' intervals={sIntervals} />
             <div style={{ paddingLeft: '30px', paddingBottom: '20px' }}>
                 {sItemsDiskio && sItemsDiskio.map((opt) => opt)}
             </div>
 
             Network IO
+            <IntervalSelect kind='net' intervals={sIntervals} />
             <div style={{ paddingLeft: '30px', paddingBottom: '20px' }}>
                 {sItemsNet && sItemsNet.map((opt) => opt)}
             </div>
 
             {sItemsProtocol && sItemsProtocol.length > 0 ? 'Protocols' : ''}
+            {sItemsProtocol && sItemsProtocol.length > 0 ? <IntervalSelect kind='proto' intervals={sIntervals} /> : ''}
             <div style={{ paddingLeft: '30px', paddingBottom: '20px' }}>
                 {sItemsProtocol && sItemsProtocol.map((opt) => opt)}
             </div>
 
             {sItemsRowsCounter && sItemsRowsCounter.length > 0 ? 'Table Rows Increments' : ''}
+            {sItemsRowsCounter && sItemsRowsCounter.length > 0 ? <IntervalSelect kind='table_rows_counter' intervals={sIntervals} /> : ''}
             <div style={{ paddingLeft: '30px', paddingBottom: '20px' }}>
                 {sItemsRowsCounter && sItemsRowsCounter.map((opt) => opt)}
             </div>
+            {sOptionalInlets && sOptionalInlets.map((info) => {
+                const key = OPTIONAL_INLETS.get(info.name);
+                const value = sOptionalValues.get(key).trim();
+                const enabled = value !== '' && value !== 'false';
+                const args = enabled && value !== 'true' ? value.split(/\s+/) : [];
+                return (
+                    <div key={info.name} style={{ paddingBottom: '20px' }}>
+                        <SlCheckbox id={`optional-${key}`} checked={enabled}>{info.desc}</SlCheckbox>
+                        <PluginArgs info={info} args={args} />
+                    </div>
+                );
+            })}
             <SlButton type="submit" variant='primary' >Update</SlButton>
         </form>
     )
 }
 
+// inlets that are disabled by default, their forms are rendered by the catalog of the plugins.
+// The config is "false", "true" or the space separated args, e.g. in_top "10 100".
+const OPTIONAL_INLETS = new Map<string, string>([
+    ['in-top', 'in_top'],
+    ['in-pressure', 'in_pressure'],
+    ['in-kernel', 'in_kernel'],
+]);
 
-function makeCheckboxList(kind: string, items: string[], selected: string[]) {
+// filters of the disk usages, the values are comma(,) separated.
+const DISK_FILTERS = [
+    { key: 'in_disk_exclude', label: 'Exclude mount points', help: 'Wildcard(*) is allowed, e.g. /snap/*,/run/*' },
+    { key: 'in_disk_fstypes', label: 'File system types', help: 'e.g. ext4,xfs' },
+    { key: 'in_disk_exclude_fstypes', label: 'Exclude file system types', help: 'e.g. tmpfs,devtmpfs,overlay,squashfs' },
+];
+
+function DiskFilters(conf: { values: Map<string, string> }) {
+    if (!conf.values) return null;
+    return (
+        <div style={{ paddingLeft: '30px', paddingBottom: '20px', maxWidth: '400px' }}>
+            {DISK_FILTERS.map((filter) => (
+                <SlInput
+                    key={filter.key}
+                    id={`disk-filter-${filter.key}`}
+                    size='small'
+                    label={filter.label}
+                    helpText={filter.help}
+                    value={conf.values.get(filter.key) || ''} />
+            ))}
+        </div>
+    );
+}
+
+// inlets that can have their own interval, the config key is `in_${kind}_interval`
+const INTERVAL_KINDS = ['disk', 'diskio', 'net', 'proto', 'table_rows_counter'];
+
+function IntervalSelect(conf: { kind: string, intervals: Map<string, string> }) {
+    if (!conf.intervals) return null;
+    return (
+        <div style={{ paddingLeft: '30px', maxWidth: '200px' }}>
+            <SlSelect
+                id={`interval-${conf.kind}`}
+                size='small'
+                placeholder='Default interval'
+                value={conf.intervals.get(conf.kind) || ''}
+                clearable>
+                <SlOption value="1s">1 sec.</SlOption>
+                <SlOption value="5s">5 sec.</SlOption>
+                <SlOption value="10s">10 sec.</SlOption>
+                <SlOption value="30s">30 sec.</SlOption>
+                <SlOption value="1m">1 min.</SlOption>
+                <SlOption value="5m">5 min.</SlOption>
+                <SlOption value="10m">10 min.</SlOption>
+            </SlSelect>
+        </div>
+    );
+}
+
+function makeCheckboxList(kind: string, items: string[], selected: string[], captions?: Map<string, string>) {
     const options: any[] = [];
-    const labels = new Map<string, string>();
     for (let i = 0; i < items.length; i++) {
         let checked: boolean = false;
         if (selected) checked = selected.includes(items[i]);
         const label: string = items[i];
-        const id = `input-${kind}-${label}`;
-        labels.set(id, label);
-        options.push(<span key={kind + i}><SlCheckbox id={id} checked={checked}>{label}</SlCheckbox><br /></span>)
+        const caption = captions && captions.has(label) ? captions.get(label) : label;
+        options.push(<span key={kind + i}><SlCheckbox id={checkboxId(kind, label)} checked={checked}>{caption}</SlCheckbox><br /></span>)
     }
-    const form = document.getElementById('inputs-form');
-    form.addEventListener('submit', (event) => {
-        event.preventDefault();
-        const values: string[] = [];
-        for (let [id, label] of labels.entries()) {
-            const cb = document.getElementById(id) as SlCheckboxElement;
-            if (!cb || !cb.checked) continue;
-            values.push(label);
-        }
-        setConfig(`in_${kind}`, values.join(',')).then((rsp: any) => {
-           //  console.log(rsp);
-        });
-    })
     return options;
 }
+
+// readCheckboxList returns the checked items of the list rendered by makeCheckboxList.
+function readCheckboxList(kind: string, items: string[]): string[] {
+    return items.filter((label) => {
+        const cb = document.getElementById(checkboxId(kind, label)) as SlCheckboxElement;
+        return cb && cb.checked;
+    });
+}
+
+function checkboxId(kind: string, label: string): string {
+    return `input-${kind}-${label}`;
+}